@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/awesome-gocui/gocui"
+)
+
+var gocuiKeys = map[string]gocui.Key{
+	"up":         gocui.KeyArrowUp,
+	"down":       gocui.KeyArrowDown,
+	"left":       gocui.KeyArrowLeft,
+	"right":      gocui.KeyArrowRight,
+	"enter":      gocui.KeyEnter,
+	"tab":        gocui.KeyTab,
+	"shift+tab":  gocui.KeyBacktab,
+	"esc":        gocui.KeyEsc,
+	"home":       gocui.KeyHome,
+	"end":        gocui.KeyEnd,
+	"pgup":       gocui.KeyPgup,
+	"pgdn":       gocui.KeyPgdn,
+	"insert":     gocui.KeyInsert,
+	"delete":     gocui.KeyDelete,
+	"ctrl+space": gocui.KeyCtrlSpace,
+	"f1":         gocui.KeyF1,
+	"f2":         gocui.KeyF2,
+	"f3":         gocui.KeyF3,
+	"f4":         gocui.KeyF4,
+	"f5":         gocui.KeyF5,
+	"f6":         gocui.KeyF6,
+	"f7":         gocui.KeyF7,
+	"f8":         gocui.KeyF8,
+	"f9":         gocui.KeyF9,
+	"f10":        gocui.KeyF10,
+	"f11":        gocui.KeyF11,
+	"f12":        gocui.KeyF12,
+}
+
+// gocuiKey translates key name from config to gocui key
+func gocuiKey(name string) (gocui.Key, error) {
+	if key, found := gocuiKeys[name]; found {
+		return key, nil
+	}
+	if len(name) == len("ctrl+x") && strings.HasPrefix(name, "ctrl+") {
+		c := name[len(name)-1]
+		if c >= 'a' && c <= 'z' {
+			return gocui.KeyCtrlA + gocui.Key(c-'a'), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown key '%s'", name)
+}
+
+// keyHelp describes keys bound to an action in the search app title and help line
+func keyHelp(keys []string) string {
+	if len(keys) == 0 {
+		return "(unbound)"
+	}
+	return strings.ToUpper(strings.Join(keys, "/"))
+}
+
+type keybinding struct {
+	keys    []string
+	handler func(*gocui.Gui, *gocui.View) error
+}
+
+func (m manager) keybindings() []keybinding {
+	kb := m.config.Keybindings
+	return []keybinding{
+		{kb.Next, m.Next},
+		{kb.Prev, m.Prev},
+		{kb.Paste, m.SelectPaste},
+		{kb.Execute, m.SelectExecute},
+		{kb.Abort, m.AbortPaste},
+		{kb.Quit, quit},
+		{kb.SwitchMode, m.SwitchModes},
+		{kb.SwitchMatching, m.SwitchMatching},
+		{kb.TimeFilter, m.SwitchTimeRange},
+		{kb.Favorite, m.ToggleFavorite},
+		{kb.Delete, m.Delete},
+		{kb.Preview, m.TogglePreview},
+		{kb.Session, m.SwitchSessionMode},
+		{kb.Select, m.ToggleMark},
+		{kb.SwitchJoin, m.SwitchJoinMode},
+		{kb.SaveScript, m.SaveScript},
+	}
+}
+
+func (m manager) setKeybindings(g *gocui.Gui) error {
+	for _, kb := range m.keybindings() {
+		for _, name := range kb.keys {
+			key, err := gocuiKey(name)
+			if err != nil {
+				return err
+			}
+			if err := g.SetKeybinding("", key, gocui.ModNone, kb.handler); err != nil {
+				return fmt.Errorf("could not bind key '%s': %w", name, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/awesome-gocui/gocui"
+	"github.com/curusarn/resh/internal/cfg"
+)
+
+func TestGocuiKey(t *testing.T) {
+	for _, name := range cfg.KeyNames() {
+		if _, err := gocuiKey(name); err != nil {
+			t.Errorf("Key from config is not supported: %v", err)
+		}
+	}
+	data := map[string]gocui.Key{
+		"ctrl+a": gocui.KeyCtrlA,
+		"ctrl+r": gocui.KeyCtrlR,
+		"ctrl+z": gocui.KeyCtrlZ,
+		"up":     gocui.KeyArrowUp,
+	}
+	for name, expected := range data {
+		if key, _ := gocuiKey(name); key != expected {
+			t.Errorf("Unexpected key for %s: %v", name, key)
+		}
+	}
+}
@@ -1,23 +1,21 @@
 package main
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
-	"net/http"
 	"os"
-	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/awesome-gocui/gocui"
 	"github.com/curusarn/resh/internal/cfg"
+	"github.com/curusarn/resh/internal/cli"
 	"github.com/curusarn/resh/internal/datadir"
 	"github.com/curusarn/resh/internal/device"
+	"github.com/curusarn/resh/internal/envinfo"
 	"github.com/curusarn/resh/internal/logger"
 	"github.com/curusarn/resh/internal/msg"
 	"github.com/curusarn/resh/internal/opt"
@@ -26,8 +24,6 @@ import (
 	"github.com/curusarn/resh/internal/searchapp"
 	"github.com/spf13/pflag"
 	"go.uber.org/zap"
-
-	"strconv"
 )
 
 // info passed during build
@@ -57,12 +53,14 @@ func main() {
 
 func runReshCli(out *output.Output, config cfg.Config) (string, int) {
 	args := opt.HandleVersionOpts(out, os.Args, version, commit)
+	searchapp.SetTheme(getTheme(config))
 
 	const missing = "<missing cdxgtcpboqwrdom>"
 	flags := pflag.NewFlagSet("", pflag.ExitOnError)
 	sessionID := flags.String("session-id", missing, "RESH generated session ID")
 	pwd := flags.String("pwd", missing, "$PWD - present working directory")
 	gitOriginRemote := flags.String("git-remote", missing, "> git remote get-url origin")
+	gitBranch := flags.String("git-branch", "", "> git rev-parse --abbrev-ref HEAD")
 	query := flags.String("query", "", "Search query")
 	flags.Parse(args)
 
@@ -86,29 +84,13 @@ func runReshCli(out *output.Output, config cfg.Config) (string, int) {
 		out.FatalE("Could not get device name", err)
 	}
 
-	g, err := gocui.NewGui(gocui.OutputNormal, false)
-	if err != nil {
-		out.FatalE("Failed to launch TUI", err)
-	}
-	defer g.Close()
-
-	g.Cursor = true
-	// g.SelFgColor = gocui.ColorGreen
-	// g.SelBgColor = gocui.ColorGreen
-	g.Highlight = true
-
-	var resp msg.CliResponse
-	mess := msg.CliMsg{
-		SessionID: *sessionID,
-		PWD:       *pwd,
-	}
-	resp = SendCliMsg(out, mess, strconv.Itoa(config.Port))
-
 	st := state{
 		// lock sync.Mutex
-		gui:          g,
-		cliRecords:   resp.Records,
 		initialQuery: *query,
+		fuzzyMode:    config.Search.Matching == cfg.MatchingFuzzy,
+		timeRange:    searchapp.TimeRangeAll,
+		sessions:     make(map[string][]recordint.SearchApp),
+		joinMode:     config.Search.Join,
 	}
 
 	// TODO: Use device ID
@@ -119,50 +101,41 @@ func runReshCli(out *output.Output, config cfg.Config) (string, int) {
 		host:            deviceName,
 		pwd:             *pwd,
 		gitOriginRemote: *gitOriginRemote,
+		gitBranch:       *gitBranch,
+		env:             envinfo.Get(config.CaptureEnv),
 		s:               &st,
 	}
-	g.SetManager(layout)
 
-	errMsg = "Failed to set keybindings"
-	if err := g.SetKeybinding("", gocui.KeyTab, gocui.ModNone, layout.Next); err != nil {
-		out.FatalE(errMsg, err)
-	}
-	if err := g.SetKeybinding("", gocui.KeyArrowDown, gocui.ModNone, layout.Next); err != nil {
-		out.FatalE(errMsg, err)
-	}
-	if err := g.SetKeybinding("", gocui.KeyCtrlN, gocui.ModNone, layout.Next); err != nil {
-		out.FatalE(errMsg, err)
-	}
-	if err := g.SetKeybinding("", gocui.KeyArrowUp, gocui.ModNone, layout.Prev); err != nil {
-		out.FatalE(errMsg, err)
+	// get initial results before launching TUI so that we can exit cleanly when daemon is not running
+	ctx := context.Background()
+	err = layout.updateData(ctx, *query)
+	if err != nil {
+		out.FatalDaemonNotRunning(err)
 	}
-	if err := g.SetKeybinding("", gocui.KeyCtrlP, gocui.ModNone, layout.Prev); err != nil {
-		out.FatalE(errMsg, err)
+	err = layout.updateRawData(ctx, *query)
+	if err != nil {
+		out.FatalDaemonNotRunning(err)
 	}
 
-	if err := g.SetKeybinding("", gocui.KeyArrowRight, gocui.ModNone, layout.SelectPaste); err != nil {
-		out.FatalE(errMsg, err)
-	}
-	if err := g.SetKeybinding("", gocui.KeyEnter, gocui.ModNone, layout.SelectExecute); err != nil {
-		out.FatalE(errMsg, err)
-	}
-	if err := g.SetKeybinding("", gocui.KeyCtrlG, gocui.ModNone, layout.AbortPaste); err != nil {
-		out.FatalE(errMsg, err)
-	}
-	if err := g.SetKeybinding("", gocui.KeyCtrlC, gocui.ModNone, quit); err != nil {
-		out.FatalE(errMsg, err)
-	}
-	if err := g.SetKeybinding("", gocui.KeyCtrlD, gocui.ModNone, quit); err != nil {
-		out.FatalE(errMsg, err)
+	// true color output mode also handles 256 and basic colors used by themes
+	g, err := gocui.NewGui(gocui.OutputTrue, false)
+	if err != nil {
+		out.FatalE("Failed to launch TUI", err)
 	}
+	defer g.Close()
+
+	g.Cursor = true
+	// g.SelFgColor = gocui.ColorGreen
+	// g.SelBgColor = gocui.ColorGreen
+	g.Highlight = true
 
-	if err := g.SetKeybinding("", gocui.KeyCtrlR, gocui.ModNone, layout.SwitchModes); err != nil {
-		out.FatalE(errMsg, err)
+	st.gui = g
+	g.SetManager(layout)
+
+	if err := layout.setKeybindings(g); err != nil {
+		out.FatalE("Failed to set keybindings", err)
 	}
 
-	ctx := context.Background()
-	layout.updateData(ctx, *query)
-	layout.updateRawData(ctx, *query)
 	err = g.MainLoop()
 	if err != nil && !errors.Is(err, gocui.ErrQuit) {
 		out.FatalE("Main application loop finished with error", err)
@@ -173,8 +146,6 @@ func runReshCli(out *output.Output, config cfg.Config) (string, int) {
 type state struct {
 	gui *gocui.Gui
 
-	cliRecords []recordint.SearchApp
-
 	lock sync.Mutex
 
 	cancelUpdate        *context.CancelFunc
@@ -184,6 +155,30 @@ type state struct {
 	displayedItemsCount int
 
 	rawMode bool
+	// fuzzyMode matches query terms as subsequences
+	fuzzyMode bool
+	// timeRange limits results to commands executed today, this week, this month or at any time
+	timeRange string
+	// previewMode shows details of the highlighted item
+	previewMode bool
+	// records of sessions shown in preview - nil value means that the session is being loaded
+	sessions map[string][]recordint.SearchApp
+
+	// sessionMode replays the session of the highlighted item
+	sessionMode     bool
+	sessionID       string
+	sessionRecordID string
+	// position in the session - -1 until the session is loaded
+	sessionCursor int
+	// start of the marked range - -1 when no range is marked
+	sessionAnchor int
+
+	// marked items in order of marking - they are selected together
+	marked []markedItem
+	// how multiple selected commands are joined
+	joinMode string
+	// message shown in the title (e.g. errors)
+	message string
 
 	initialQuery string
 
@@ -191,6 +186,11 @@ type state struct {
 	exitCode int
 }
 
+type markedItem struct {
+	key     string
+	cmdLine string
+}
+
 type manager struct {
 	out    *output.Output
 	config cfg.Config
@@ -199,6 +199,8 @@ type manager struct {
 	host            string
 	pwd             string
 	gitOriginRemote string
+	gitBranch       string
+	env             map[string]string
 
 	s *state
 }
@@ -206,6 +208,12 @@ type manager struct {
 func (m manager) SelectExecute(g *gocui.Gui, v *gocui.View) error {
 	m.s.lock.Lock()
 	defer m.s.lock.Unlock()
+	if m.s.sessionMode {
+		return m.selectSessionRange(exitCodeExecute)
+	}
+	if len(m.s.marked) > 0 {
+		return m.selectMarked(exitCodeExecute)
+	}
 	if m.s.rawMode {
 		if m.s.highlightedItem < len(m.s.rawData) {
 			m.s.output = m.s.rawData[m.s.highlightedItem].CmdLineOut
@@ -225,6 +233,12 @@ func (m manager) SelectExecute(g *gocui.Gui, v *gocui.View) error {
 func (m manager) SelectPaste(g *gocui.Gui, v *gocui.View) error {
 	m.s.lock.Lock()
 	defer m.s.lock.Unlock()
+	if m.s.sessionMode {
+		return m.selectSessionRange(0)
+	}
+	if len(m.s.marked) > 0 {
+		return m.selectMarked(0)
+	}
 	if m.s.rawMode {
 		if m.s.highlightedItem < len(m.s.rawData) {
 			m.s.output = m.s.rawData[m.s.highlightedItem].CmdLineOut
@@ -241,6 +255,120 @@ func (m manager) SelectPaste(g *gocui.Gui, v *gocui.View) error {
 	return nil
 }
 
+// getHighlightedCmdLine returns key and command line of the highlighted item in any mode
+// Has to be called with the state locked
+func (m manager) getHighlightedCmdLine() (string, string, bool) {
+	if m.s.highlightedItem < 0 {
+		return "", "", false
+	}
+	if m.s.rawMode {
+		if m.s.highlightedItem >= len(m.s.rawData) {
+			return "", "", false
+		}
+		itm := m.s.rawData[m.s.highlightedItem]
+		return itm.Key, itm.CmdLineOut, true
+	}
+	if m.s.highlightedItem >= len(m.s.data) {
+		return "", "", false
+	}
+	itm := m.s.data[m.s.highlightedItem]
+	return itm.Key, itm.CmdLineOut, true
+}
+
+// isMarked has to be called with the state locked
+func (m manager) isMarked(key string) bool {
+	for _, itm := range m.s.marked {
+		if itm.key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// ToggleMark marks the highlighted item so that it's selected together with other marked items
+// Marks are kept when the query changes
+func (m manager) ToggleMark(g *gocui.Gui, v *gocui.View) error {
+	m.s.lock.Lock()
+	defer m.s.lock.Unlock()
+	if m.s.sessionMode {
+		return nil
+	}
+	key, cmdLine, ok := m.getHighlightedCmdLine()
+	if !ok {
+		return nil
+	}
+	var marked []markedItem
+	for _, itm := range m.s.marked {
+		if itm.key != key {
+			marked = append(marked, itm)
+		}
+	}
+	if len(marked) == len(m.s.marked) {
+		marked = append(marked, markedItem{key: key, cmdLine: cmdLine})
+	}
+	m.s.marked = marked
+	if m.s.highlightedItem < m.s.displayedItemsCount-1 {
+		m.s.highlightedItem++
+	}
+	return nil
+}
+
+// SwitchJoinMode switches how multiple selected commands are joined
+func (m manager) SwitchJoinMode(g *gocui.Gui, v *gocui.View) error {
+	m.s.lock.Lock()
+	defer m.s.lock.Unlock()
+	m.s.joinMode = nextJoinMode(m.s.joinMode)
+	return nil
+}
+
+// selectMarked outputs marked commands
+// Has to be called with the state locked
+func (m manager) selectMarked(exitCode int) error {
+	var cmdLines []string
+	for _, itm := range m.s.marked {
+		cmdLines = append(cmdLines, itm.cmdLine)
+	}
+	m.s.output = joinCmdLines(cmdLines, m.s.joinMode)
+	m.s.exitCode = exitCode
+	return gocui.ErrQuit
+}
+
+// SaveScript saves selected commands into an executable script in the current directory
+// Path to the script is pasted onto the command line
+func (m manager) SaveScript(g *gocui.Gui, v *gocui.View) error {
+	m.s.lock.Lock()
+	defer m.s.lock.Unlock()
+	var cmdLines []string
+	if m.s.sessionMode {
+		if m.s.sessionCursor == -1 {
+			return nil
+		}
+		start, end := m.getSessionRange()
+		for _, rec := range m.s.sessions[m.s.sessionID][start:end] {
+			cmdLines = append(cmdLines, rec.CmdLine)
+		}
+	} else if len(m.s.marked) > 0 {
+		for _, itm := range m.s.marked {
+			cmdLines = append(cmdLines, itm.cmdLine)
+		}
+	} else {
+		_, cmdLine, ok := m.getHighlightedCmdLine()
+		if !ok {
+			return nil
+		}
+		cmdLines = []string{cmdLine}
+	}
+	fpath, err := writeScript(m.pwd, cmdLines, time.Now())
+	if err != nil {
+		m.out.Logger.Sugar().Errorw("Failed to save script", zap.Error(err))
+		m.s.message = "FAILED TO SAVE SCRIPT: " + err.Error()
+		return nil
+	}
+	m.s.output = fpath
+	m.s.exitCode = 0 // success
+	return gocui.ErrQuit
+}
+
 func (m manager) AbortPaste(g *gocui.Gui, v *gocui.View) error {
 	m.s.lock.Lock()
 	defer m.s.lock.Unlock()
@@ -252,124 +380,203 @@ func (m manager) AbortPaste(g *gocui.Gui, v *gocui.View) error {
 	return nil
 }
 
-func (m manager) updateData(ctx context.Context, input string) {
+// ToggleFavorite flags highlighted item as favorite or removes the flag
+func (m manager) ToggleFavorite(g *gocui.Gui, v *gocui.View) error {
+	itm, ok := m.getHighlightedItem()
+	if !ok {
+		return nil
+	}
+	m.flag(recordint.Flag{
+		CmdLine: itm.CmdLineOut,
+		Name:    recordint.FlagFavorite,
+		Value:   !itm.Favorite,
+	}, v.Buffer())
+	return nil
+}
+
+// Delete flags highlighted item as deleted - it will no longer show up in search
+func (m manager) Delete(g *gocui.Gui, v *gocui.View) error {
+	itm, ok := m.getHighlightedItem()
+	if !ok {
+		return nil
+	}
+	m.flag(recordint.Flag{
+		CmdLine: itm.CmdLineOut,
+		Name:    recordint.FlagDeleted,
+		Value:   true,
+	}, v.Buffer())
+	return nil
+}
+
+// getHighlightedItem returns highlighted item if there is one that can be flagged
+// Only items from contextual mode can be flagged
+func (m manager) getHighlightedItem() (searchapp.Item, bool) {
+	m.s.lock.Lock()
+	defer m.s.lock.Unlock()
+	if m.s.rawMode || m.s.highlightedItem >= len(m.s.data) || m.s.highlightedItem < 0 {
+		return searchapp.Item{}, false
+	}
+	itm := m.s.data[m.s.highlightedItem]
+	// only saved records can be flagged
+	if itm.Record.RecordID == "" {
+		return searchapp.Item{}, false
+	}
+	return itm, true
+}
+
+func (m manager) flag(flag recordint.Flag, input string) {
+	sugar := m.out.Logger.Sugar()
+	resp, err := cli.SendFlagMsg(flag, m.config)
+	if err != nil {
+		sugar.Errorw("Failed to flag records", zap.Error(err),
+			"flag", flag.Name,
+		)
+		return
+	}
+	sugar.Infow("Records flagged",
+		"flag", flag.Name,
+		"value", flag.Value,
+		"flaggedCount", resp.FlaggedCount,
+		"hiddenCount", resp.HiddenCount,
+	)
+	if resp.HiddenCount > 0 {
+		// history files of other devices are not modified
+		m.s.lock.Lock()
+		if resp.FlaggedCount == 0 {
+			m.s.message = "COMMAND IS ONLY IN HISTORY OF OTHER DEVICES - IT'S HIDDEN UNTIL DAEMON RESTART"
+		} else {
+			m.s.message = fmt.Sprintf("DELETED - %d COPIES IN HISTORY OF OTHER DEVICES ARE ONLY HIDDEN", resp.HiddenCount)
+		}
+		m.s.lock.Unlock()
+	}
+	// daemon applied the flag - get fresh results
+	go m.update(input)
+}
+
+// maximal number of items that are kept for display
+const itemLimit = 420
+
+func (m manager) isFuzzyMode() bool {
+	m.s.lock.Lock()
+	defer m.s.lock.Unlock()
+	return m.s.fuzzyMode
+}
+
+func (m manager) isRawMode() bool {
+	m.s.lock.Lock()
+	defer m.s.lock.Unlock()
+	return m.s.rawMode
+}
+
+func (m manager) getTimeRange() string {
+	m.s.lock.Lock()
+	defer m.s.lock.Unlock()
+	return m.s.timeRange
+}
+
+func (m manager) search(ctx context.Context, input string, raw bool, fuzzy bool) ([]searchapp.Result, error) {
+	// daemon filters by time before scoring
+	filter := searchapp.Filter{Since: searchapp.TimeRangeStart(m.getTimeRange(), time.Now())}
+	mess := msg.SearchMsg{
+		SessionID:       m.sessionID,
+		Query:           input,
+		Raw:             raw,
+		Fuzzy:           fuzzy,
+		PWD:             m.pwd,
+		GitOriginRemote: m.gitOriginRemote,
+		GitBranch:       m.gitBranch,
+		Env:             m.env,
+		Options:         searchapp.Options{Filter: filter, Limit: itemLimit},
+	}
+	return cli.Search(ctx, mess, m.config)
+}
+
+func (m manager) updateData(ctx context.Context, input string) error {
 	timeStart := time.Now()
 	sugar := m.out.Logger.Sugar()
 	sugar.Debugw("Starting data update ...",
-		"recordCount", len(m.s.cliRecords),
 		"itemCount", len(m.s.data),
 	)
-	query := searchapp.NewQueryFromString(sugar, input, m.host, m.pwd, m.gitOriginRemote, m.config.Debug)
-	var data []searchapp.Item
-	itemSet := make(map[string]int)
-	for _, rec := range m.s.cliRecords {
+	fuzzy := m.isFuzzyMode()
+	results, err := m.search(ctx, input, false, fuzzy)
+	if err != nil {
 		if shouldCancel(ctx) {
-			timeEnd := time.Now()
 			sugar.Infow("Update got canceled",
-				"duration", timeEnd.Sub(timeStart),
+				"duration", time.Since(timeStart),
 			)
-			return
+			return nil
 		}
-		itm, err := searchapp.NewItemFromRecordForQuery(rec, query, m.config.Debug)
+		sugar.Errorw("Search failed", zap.Error(err))
+		return err
+	}
+	// items are rendered locally - daemon only sends the best records
+	qctx := searchapp.QueryContext{
+		Host:            m.host,
+		Pwd:             m.pwd,
+		GitOriginRemote: m.gitOriginRemote,
+		GitBranch:       m.gitBranch,
+		SessionID:       m.sessionID,
+		Env:             m.env,
+	}
+	query := searchapp.NewQueryFromString(sugar, input, qctx, m.config.Search.Ranking, fuzzy, m.config.Debug)
+	var data []searchapp.Item
+	for _, res := range results {
+		itm, err := searchapp.NewItemFromRecordForQuery(res.Record, query, m.config.Debug)
 		if err != nil {
-			// records didn't match the query
-			// sugar.Println(" * continue (no match)", rec.Pwd)
-			continue
-		}
-		if idx, ok := itemSet[itm.Key]; ok {
-			// duplicate found
-			if data[idx].Score >= itm.Score {
-				// skip duplicate item
-				continue
-			}
-			// update duplicate item
-			data[idx] = itm
 			continue
 		}
-		// add new item
-		itemSet[itm.Key] = len(data)
+		itm.Stats = res.Stats
 		data = append(data, itm)
 	}
-	sugar.Debugw("Got new items from records for query, sorting items ...",
-		"itemCount", len(data),
-	)
-	sort.SliceStable(data, func(p, q int) bool {
-		return data[p].Score > data[q].Score
-	})
 
 	m.s.lock.Lock()
 	defer m.s.lock.Unlock()
-	m.s.data = nil
-	for _, itm := range data {
-		if len(m.s.data) > 420 {
-			break
-		}
-		m.s.data = append(m.s.data, itm)
-	}
+	m.s.data = data
 	m.s.highlightedItem = 0
-	timeEnd := time.Now()
 	sugar.Debugw("Done with data update",
-		"duration", timeEnd.Sub(timeStart),
-		"recordCount", len(m.s.cliRecords),
+		"duration", time.Since(timeStart),
 		"itemCount", len(m.s.data),
 		"input", input,
 	)
+	return nil
 }
 
-func (m manager) updateRawData(ctx context.Context, input string) {
+func (m manager) updateRawData(ctx context.Context, input string) error {
 	timeStart := time.Now()
 	sugar := m.out.Logger.Sugar()
 	sugar.Debugw("Starting RAW data update ...",
-		"recordCount", len(m.s.cliRecords),
-		"itemCount", len(m.s.data),
+		"itemCount", len(m.s.rawData),
 	)
-	query := searchapp.GetRawTermsFromString(input, m.config.Debug)
-	var data []searchapp.RawItem
-	itemSet := make(map[string]bool)
-	for _, rec := range m.s.cliRecords {
+	fuzzy := m.isFuzzyMode()
+	results, err := m.search(ctx, input, true, fuzzy)
+	if err != nil {
 		if shouldCancel(ctx) {
-			timeEnd := time.Now()
 			sugar.Debugw("Update got canceled",
-				"duration", timeEnd.Sub(timeStart),
+				"duration", time.Since(timeStart),
 			)
-			return
+			return nil
 		}
-		itm, err := searchapp.NewRawItemFromRecordForQuery(rec, query, m.config.Debug)
+		sugar.Errorw("Search failed", zap.Error(err))
+		return err
+	}
+	query := searchapp.NewRawQueryFromString(input, fuzzy, m.config.Debug)
+	var data []searchapp.RawItem
+	for _, res := range results {
+		itm, err := searchapp.NewRawItemFromRecordForQuery(res.Record, query, m.config.Debug)
 		if err != nil {
-			// records didn't match the query
-			// sugar.Println(" * continue (no match)", rec.Pwd)
-			continue
-		}
-		if itemSet[itm.Key] {
-			// sugar.Println(" * continue (already present)", itm.key(), itm.pwd)
 			continue
 		}
-		itemSet[itm.Key] = true
 		data = append(data, itm)
-		// sugar.Println("DATA =", itm.display)
 	}
-	sugar.Debugw("Got new RAW items from records for query, sorting items ...",
-		"itemCount", len(data),
-	)
-	sort.SliceStable(data, func(p, q int) bool {
-		return data[p].Score > data[q].Score
-	})
 	m.s.lock.Lock()
 	defer m.s.lock.Unlock()
-	m.s.rawData = nil
-	for _, itm := range data {
-		if len(m.s.rawData) > 420 {
-			break
-		}
-		m.s.rawData = append(m.s.rawData, itm)
-	}
+	m.s.rawData = data
 	m.s.highlightedItem = 0
-	timeEnd := time.Now()
 	sugar.Debugw("Done with RAW data update",
-		"duration", timeEnd.Sub(timeStart),
-		"recordCount", len(m.s.cliRecords),
-		"itemCount", len(m.s.data),
+		"duration", time.Since(timeStart),
+		"itemCount", len(m.s.rawData),
 	)
+	return nil
 }
 
 func shouldCancel(ctx context.Context) bool {
@@ -394,7 +601,7 @@ func (m manager) getCtxAndCancel() context.Context {
 
 func (m manager) update(input string) {
 	ctx := m.getCtxAndCancel()
-	if m.s.rawMode {
+	if m.isRawMode() {
 		m.updateRawData(ctx, input)
 	} else {
 		m.updateData(ctx, input)
@@ -408,6 +615,17 @@ func (m manager) flush() {
 }
 
 func (m manager) Edit(v *gocui.View, key gocui.Key, ch rune, mod gocui.Modifier) {
+	m.s.lock.Lock()
+	sessionMode := m.s.sessionMode
+	m.s.message = ""
+	m.s.lock.Unlock()
+	if sessionMode {
+		// query can't be edited in session replay
+		if key == gocui.KeySpace || ch == ' ' {
+			m.MarkSessionRange(nil, v)
+		}
+		return
+	}
 	gocui.DefaultEditor.Edit(v, key, ch, mod)
 	go m.update(v.Buffer())
 }
@@ -415,6 +633,12 @@ func (m manager) Edit(v *gocui.View, key gocui.Key, ch rune, mod gocui.Modifier)
 func (m manager) Next(g *gocui.Gui, v *gocui.View) error {
 	m.s.lock.Lock()
 	defer m.s.lock.Unlock()
+	if m.s.sessionMode {
+		if m.s.sessionCursor != -1 && m.s.sessionCursor < len(m.s.sessions[m.s.sessionID])-1 {
+			m.s.sessionCursor++
+		}
+		return nil
+	}
 	if m.s.highlightedItem < m.s.displayedItemsCount-1 {
 		m.s.highlightedItem++
 	}
@@ -424,6 +648,12 @@ func (m manager) Next(g *gocui.Gui, v *gocui.View) error {
 func (m manager) Prev(g *gocui.Gui, v *gocui.View) error {
 	m.s.lock.Lock()
 	defer m.s.lock.Unlock()
+	if m.s.sessionMode {
+		if m.s.sessionCursor > 0 {
+			m.s.sessionCursor--
+		}
+		return nil
+	}
 	if m.s.highlightedItem > 0 {
 		m.s.highlightedItem--
 	}
@@ -439,6 +669,145 @@ func (m manager) SwitchModes(g *gocui.Gui, v *gocui.View) error {
 	return nil
 }
 
+// SwitchMatching switches between exact and fuzzy matching
+func (m manager) SwitchMatching(g *gocui.Gui, v *gocui.View) error {
+	m.s.lock.Lock()
+	m.s.fuzzyMode = !m.s.fuzzyMode
+	m.s.lock.Unlock()
+
+	go m.update(v.Buffer())
+	return nil
+}
+
+// time ranges are switched in this order
+var timeRanges = []string{searchapp.TimeRangeAll, searchapp.TimeRangeToday, searchapp.TimeRangeWeek, searchapp.TimeRangeMonth}
+
+var timeRangeTitles = map[string]string{
+	searchapp.TimeRangeToday: "TODAY",
+	searchapp.TimeRangeWeek:  "THIS WEEK",
+	searchapp.TimeRangeMonth: "THIS MONTH",
+}
+
+// SwitchTimeRange cycles through time ranges of the results
+func (m manager) SwitchTimeRange(g *gocui.Gui, v *gocui.View) error {
+	m.s.lock.Lock()
+	next := timeRanges[0]
+	for i, timeRange := range timeRanges {
+		if timeRange == m.s.timeRange {
+			next = timeRanges[(i+1)%len(timeRanges)]
+		}
+	}
+	m.s.timeRange = next
+	m.s.lock.Unlock()
+
+	go m.update(v.Buffer())
+	return nil
+}
+
+// TogglePreview shows or hides the preview pane
+func (m manager) TogglePreview(g *gocui.Gui, v *gocui.View) error {
+	m.s.lock.Lock()
+	defer m.s.lock.Unlock()
+	m.s.previewMode = !m.s.previewMode
+	return nil
+}
+
+// getSession returns cached session records or starts loading them
+// Has to be called with the state locked
+func (m manager) getSession(sessionID string) []recordint.SearchApp {
+	recs, found := m.s.sessions[sessionID]
+	if found {
+		return recs
+	}
+	m.s.sessions[sessionID] = nil
+	go func() {
+		sugar := m.out.Logger.Sugar()
+		// empty non-nil slice marks the session as loaded
+		recs := []recordint.SearchApp{}
+		resp, err := cli.GetSession(sessionID, m.config)
+		if err != nil {
+			sugar.Errorw("Failed to get session records", zap.Error(err),
+				"sessionID", sessionID,
+			)
+		} else {
+			recs = append(recs, resp.Records...)
+		}
+		m.s.lock.Lock()
+		m.s.sessions[sessionID] = recs
+		m.s.lock.Unlock()
+		m.flush()
+	}()
+	return nil
+}
+
+const minPreviewHeight = 8
+
+// SwitchSessionMode starts replay of the session of the highlighted item or switches back to search
+func (m manager) SwitchSessionMode(g *gocui.Gui, v *gocui.View) error {
+	m.s.lock.Lock()
+	defer m.s.lock.Unlock()
+	if m.s.sessionMode {
+		m.s.sessionMode = false
+		return nil
+	}
+	if m.s.rawMode || m.s.highlightedItem < 0 || m.s.highlightedItem >= len(m.s.data) {
+		return nil
+	}
+	rec := m.s.data[m.s.highlightedItem].Record
+	if rec.SessionID == "" {
+		return nil
+	}
+	m.s.sessionMode = true
+	m.s.sessionID = rec.SessionID
+	m.s.sessionRecordID = rec.RecordID
+	m.s.sessionCursor = -1
+	m.s.sessionAnchor = -1
+	m.getSession(rec.SessionID)
+	return nil
+}
+
+// MarkSessionRange marks start of the range of commands at the cursor or removes the mark
+func (m manager) MarkSessionRange(g *gocui.Gui, v *gocui.View) error {
+	m.s.lock.Lock()
+	defer m.s.lock.Unlock()
+	if m.s.sessionAnchor != -1 {
+		m.s.sessionAnchor = -1
+	} else {
+		m.s.sessionAnchor = m.s.sessionCursor
+	}
+	return nil
+}
+
+// getSessionRange returns start and end (exclusive) of selected commands in the session
+// Has to be called with the state locked
+func (m manager) getSessionRange() (int, int) {
+	start, end := m.s.sessionCursor, m.s.sessionCursor
+	if m.s.sessionAnchor != -1 {
+		if m.s.sessionAnchor < start {
+			start = m.s.sessionAnchor
+		} else {
+			end = m.s.sessionAnchor
+		}
+	}
+	return start, end + 1
+}
+
+// selectSessionRange outputs selected commands from the session - one command per line
+// Has to be called with the state locked
+func (m manager) selectSessionRange(exitCode int) error {
+	if m.s.sessionCursor == -1 {
+		return nil
+	}
+	start, end := m.getSessionRange()
+	var cmdLines []string
+	for _, rec := range m.s.sessions[m.s.sessionID][start:end] {
+		cmdLines = append(cmdLines, rec.CmdLine)
+	}
+	m.s.output = joinCmdLines(cmdLines, m.s.joinMode)
+	m.s.exitCode = exitCode
+	return gocui.ErrQuit
+}
+
 func (m manager) Layout(g *gocui.Gui) error {
 	var b byte
 	maxX, maxY := g.Size()
@@ -450,23 +819,59 @@ func (m manager) Layout(g *gocui.Gui) error {
 
 	v.Editable = true
 	v.Editor = m
-	if m.s.rawMode {
-		v.Title = " RESH SEARCH - NON-CONTEXTUAL \"RAW\" MODE - (CTRL+R to switch BACK) "
+
+	// state is changed by keybindings and data updates running in other goroutines
+	m.s.lock.Lock()
+	defer m.s.lock.Unlock()
+	matching := "EXACT"
+	if m.s.fuzzyMode {
+		matching = "FUZZY"
+	}
+	if title, found := timeRangeTitles[m.s.timeRange]; found {
+		matching += " - " + title
+	}
+	join := "JOIN " + joinModeTitle(m.s.joinMode)
+	kb := m.config.Keybindings
+	selection := ""
+	if len(m.s.marked) > 0 {
+		selection = strconv.Itoa(len(m.s.marked)) + " SELECTED - " + join + " - "
+	}
+	if m.s.message != "" {
+		v.Title = " " + m.s.message + " "
+	} else if m.s.sessionMode {
+		v.Title = " RESH SESSION REPLAY - " + join + " - (SPACE to mark range, " + keyHelp(kb.Paste) + " to paste, " +
+			keyHelp(kb.Execute) + " to execute, " + keyHelp(kb.Session) + " to switch BACK) "
+	} else if len(selection) > 0 {
+		v.Title = " RESH SEARCH - " + selection + "(" + keyHelp(kb.Select) + " to select, " +
+			keyHelp(kb.SwitchJoin) + " to switch join, " + keyHelp(kb.SaveScript) + " to save as script) "
+	} else if m.s.rawMode {
+		v.Title = " RESH SEARCH - NON-CONTEXTUAL \"RAW\" MODE - " + matching + " - (" + keyHelp(kb.SwitchMode) + " to switch BACK, " +
+			keyHelp(kb.SwitchMatching) + " to switch matching) "
 	} else {
-		v.Title = " RESH SEARCH - CONTEXTUAL MODE - (CTRL+R to switch to RAW MODE) "
+		v.Title = " RESH SEARCH - CONTEXTUAL MODE - " + matching + " - (" + keyHelp(kb.SwitchMode) + " to switch to RAW MODE, " +
+			keyHelp(kb.SwitchMatching) + " to switch matching) "
 	}
 
 	g.SetCurrentView("input")
 
-	m.s.lock.Lock()
-	defer m.s.lock.Unlock()
 	if len(m.s.initialQuery) > 0 {
 		v.WriteString(m.s.initialQuery)
 		v.SetCursor(len(m.s.initialQuery), 0)
 		m.s.initialQuery = ""
 	}
 
-	v, err = g.SetView("body", 0, 2, maxX-1, maxY, b)
+	bodyMaxY := maxY
+	showPreview := m.s.previewMode && !m.s.rawMode && !m.s.sessionMode && maxY/2 >= minPreviewHeight
+	if showPreview {
+		bodyMaxY = maxY - maxY/2
+	} else {
+		err = g.DeleteView("preview")
+		if err != nil && !errors.Is(err, gocui.ErrUnknownView) {
+			m.out.FatalE("Failed to delete view 'preview'", err)
+		}
+	}
+
+	v, err = g.SetView("body", 0, 2, maxX-1, bodyMaxY, b)
 	if err != nil && !errors.Is(err, gocui.ErrUnknownView) {
 		m.out.FatalE("Failed to set view 'body'", err)
 	}
@@ -475,10 +880,81 @@ func (m manager) Layout(g *gocui.Gui) error {
 	v.Clear()
 	v.Rewind()
 
+	if m.s.sessionMode {
+		return m.replayMode(v, maxX-1, maxY-3)
+	}
 	if m.s.rawMode {
 		return m.rawMode(g, v)
 	}
-	return m.normalMode(g, v)
+	err = m.normalMode(g, v, maxX, bodyMaxY)
+	if err != nil || !showPreview {
+		return err
+	}
+
+	v, err = g.SetView("preview", 0, bodyMaxY, maxX-1, maxY-1, b)
+	if err != nil && !errors.Is(err, gocui.ErrUnknownView) {
+		m.out.FatalE("Failed to set view 'preview'", err)
+	}
+	v.Title = " PREVIEW - (" + keyHelp(m.config.Keybindings.Preview) + " to close) "
+	v.Clear()
+	v.Rewind()
+	return m.previewMode(v, maxX-2, maxY-bodyMaxY-2)
+}
+
+// replayMode shows the whole session with the cursor in the middle of the page
+func (m manager) replayMode(v *gocui.View, width, height int) error {
+	session := m.getSession(m.s.sessionID)
+	if session == nil {
+		v.WriteString("loading ...")
+		return nil
+	}
+	if m.s.sessionCursor == -1 {
+		for i, rec := range session {
+			if rec.RecordID == m.s.sessionRecordID {
+				m.s.sessionCursor = i
+				break
+			}
+		}
+		if m.s.sessionCursor == -1 {
+			if len(session) == 0 {
+				v.WriteString("- no commands found in the session -")
+				return nil
+			}
+			m.s.sessionCursor = len(session) - 1
+		}
+	}
+	first := m.s.sessionCursor - height/2
+	if first > len(session)-height {
+		first = len(session) - height
+	}
+	if first < 0 {
+		first = 0
+	}
+	start, end := m.getSessionRange()
+	for i := first; i < len(session) && i < first+height; i++ {
+		line := searchapp.DrawSessionLine(session[i], i >= start && i < end && m.s.sessionAnchor != -1, width)
+		if i == m.s.sessionCursor {
+			line = searchapp.DoHighlightString(line, width*2)
+		}
+		v.WriteString(line + "\n")
+	}
+	return nil
+}
+
+func (m manager) previewMode(v *gocui.View, width, height int) error {
+	if m.s.highlightedItem < 0 || m.s.highlightedItem >= len(m.s.data) {
+		v.WriteString("- no result selected -")
+		return nil
+	}
+	itm := m.s.data[m.s.highlightedItem]
+	var session []recordint.SearchApp
+	if itm.Record.SessionID != "" {
+		session = m.getSession(itm.Record.SessionID)
+	}
+	for _, line := range itm.DrawPreview(session, height, width) {
+		v.WriteString(line + "\n")
+	}
+	return nil
 }
 
 func quit(g *gocui.Gui, v *gocui.View) error {
@@ -487,9 +963,8 @@ func quit(g *gocui.Gui, v *gocui.View) error {
 
 const smallTerminalThresholdWidth = 110
 
-func (m manager) normalMode(g *gocui.Gui, v *gocui.View) error {
+func (m manager) normalMode(g *gocui.Gui, v *gocui.View, maxX, maxY int) error {
 	sugar := m.out.Logger.Sugar()
-	maxX, maxY := g.Size()
 
 	compactRenderingMode := false
 	if maxX < smallTerminalThresholdWidth {
@@ -500,6 +975,11 @@ func (m manager) normalMode(g *gocui.Gui, v *gocui.View) error {
 
 	header := searchapp.GetHeader(compactRenderingMode)
 	longestDateLen := len(header.Date)
+	longestFirstSeenLen := 0
+	if !compactRenderingMode {
+		longestFirstSeenLen = len(header.FirstSeen)
+	}
+	longestCountLen := len(header.Count)
 	longestLocationLen := len(header.Host) + 1 + len(header.PwdTilde)
 	longestFlagsLen := 2
 	maxPossibleMainViewHeight := maxY - 3 - 1 - 1 - 1 // - top box - header - status - help
@@ -508,6 +988,9 @@ func (m manager) normalMode(g *gocui.Gui, v *gocui.View) error {
 			break
 		}
 		ic := itm.DrawItemColumns(compactRenderingMode, m.config.Debug)
+		if m.isMarked(itm.Key) {
+			ic.Mark()
+		}
 		data = append(data, ic)
 		if i > maxPossibleMainViewHeight {
 			// do not stretch columns because of results that will end up outside of the page
@@ -516,6 +999,12 @@ func (m manager) normalMode(g *gocui.Gui, v *gocui.View) error {
 		if len(ic.Date) > longestDateLen {
 			longestDateLen = len(ic.Date)
 		}
+		if longestFirstSeenLen > 0 && len(ic.FirstSeen) > longestFirstSeenLen {
+			longestFirstSeenLen = len(ic.FirstSeen)
+		}
+		if len(ic.Count) > longestCountLen {
+			longestCountLen = len(ic.Count)
+		}
 		if len(ic.Host)+len(ic.PwdTilde) > longestLocationLen {
 			longestLocationLen = len(ic.Host) + len(ic.PwdTilde)
 		}
@@ -543,8 +1032,13 @@ func (m manager) normalMode(g *gocui.Gui, v *gocui.View) error {
 	var statusLineHeight int = len(statusLine)
 
 	helpLineHeight := 1
-	const helpLine = "HELP: type to search, UP/DOWN or CTRL+P/N to select, RIGHT to edit, ENTER to execute, CTRL+G to abort, CTRL+C/D to quit; " +
-		"FLAGS: G = this git repo, E# = exit status #"
+	kb := m.config.Keybindings
+	helpLine := "HELP: type to search, " + keyHelp(kb.Prev) + " and " + keyHelp(kb.Next) + " to select, " +
+		keyHelp(kb.Paste) + " to edit, " + keyHelp(kb.Execute) + " to execute, " + keyHelp(kb.Abort) + " to abort, " +
+		keyHelp(kb.Quit) + " to quit, " + keyHelp(kb.Favorite) + " to toggle favorite, " + keyHelp(kb.Delete) + " to delete, " +
+		keyHelp(kb.Preview) + " to toggle preview, " + keyHelp(kb.Select) + " to select multiple, " +
+		keyHelp(kb.TimeFilter) + " to filter by time; " +
+		"FLAGS: G = this git repo, F = favorite, E# = exit status #, * = selected"
 		// "TIP: when resh-cli is launched command line is used as initial search query"
 
 	mainViewHeight := maxY - topBoxHeight - statusLineHeight - helpLineHeight
@@ -553,7 +1047,7 @@ func (m manager) normalMode(g *gocui.Gui, v *gocui.View) error {
 	// header
 	// header := getHeader()
 	// error is expected for header
-	dispStr, _, _ := header.ProduceLine(longestDateLen, longestLocationLen, longestFlagsLen, true, true, m.config.Debug)
+	dispStr, _, _ := header.ProduceLine(longestDateLen, longestFirstSeenLen, longestCountLen, longestLocationLen, longestFlagsLen, true, true, m.config.Debug)
 	dispStr = searchapp.DoHighlightHeader(dispStr, maxX*2)
 	v.WriteString(dispStr + "\n")
 
@@ -569,7 +1063,7 @@ func (m manager) normalMode(g *gocui.Gui, v *gocui.View) error {
 			break
 		}
 
-		displayStr, _, err := itm.ProduceLine(longestDateLen, longestLocationLen, longestFlagsLen, false, true, m.config.Debug)
+		displayStr, _, err := itm.ProduceLine(longestDateLen, longestFirstSeenLen, longestCountLen, longestLocationLen, longestFlagsLen, false, true, m.config.Debug)
 		if err != nil {
 			sugar.Error("Error while drawing item", zap.Error(err))
 		}
@@ -610,6 +1104,9 @@ func (m manager) rawMode(g *gocui.Gui, v *gocui.View) error {
 			break
 		}
 		displayStr := itm.CmdLineWithColor
+		if m.isMarked(itm.Key) {
+			displayStr = "* " + displayStr
+		}
 		if m.s.highlightedItem == i {
 			// Use actual min required length instead of 420 constant
 			displayStr = searchapp.DoHighlightString(displayStr, maxX*2)
@@ -625,45 +1122,3 @@ func (m manager) rawMode(g *gocui.Gui, v *gocui.View) error {
 	)
 	return nil
 }
-
-// SendCliMsg to daemon
-func SendCliMsg(out *output.Output, m msg.CliMsg, port string) msg.CliResponse {
-	sugar := out.Logger.Sugar()
-	recJSON, err := json.Marshal(m)
-	if err != nil {
-		out.FatalE("Failed to marshal message", err)
-	}
-
-	req, err := http.NewRequest(
-		"POST",
-		"http://localhost:"+port+"/dump",
-		bytes.NewBuffer(recJSON))
-	if err != nil {
-		out.FatalE("Failed to build request", err)
-	}
-	req.Header.Set("Content-Type", "application/json")
-
-	client := http.Client{
-		Timeout: 3 * time.Second,
-	}
-	resp, err := client.Do(req)
-	if err != nil {
-		out.FatalDaemonNotRunning(err)
-	}
-
-	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		out.FatalE("Failed read response", err)
-	}
-	// sugar.Println(string(body))
-	response := msg.CliResponse{}
-	err = json.Unmarshal(body, &response)
-	if err != nil {
-		out.FatalE("Failed decode response", err)
-	}
-	sugar.Debugw("Received records from daemon",
-		"recordCount", len(response.Records),
-	)
-	return response
-}
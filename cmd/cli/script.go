@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/curusarn/resh/internal/cfg"
+)
+
+// join modes are switched in this order
+var joinModes = []string{cfg.JoinNewline, cfg.JoinAnd, cfg.JoinSemicolon}
+
+func nextJoinMode(mode string) string {
+	for i, m := range joinModes {
+		if m == mode {
+			return joinModes[(i+1)%len(joinModes)]
+		}
+	}
+	return joinModes[0]
+}
+
+// joinModeTitle is shown in the search app title
+func joinModeTitle(mode string) string {
+	switch mode {
+	case cfg.JoinAnd:
+		return "&&"
+	case cfg.JoinSemicolon:
+		return ";"
+	default:
+		return "NEWLINE"
+	}
+}
+
+func trimCmdLines(cmdLines []string) []string {
+	trimmed := make([]string, 0, len(cmdLines))
+	for _, cmdLine := range cmdLines {
+		trimmed = append(trimmed, strings.TrimSpace(cmdLine))
+	}
+	return trimmed
+}
+
+// joinCmdLines joins commands so that they can be pasted onto the command line at once
+func joinCmdLines(cmdLines []string, mode string) string {
+	separator := "\n"
+	switch mode {
+	case cfg.JoinAnd:
+		separator = " && "
+	case cfg.JoinSemicolon:
+		separator = "; "
+	}
+	return strings.Join(trimCmdLines(cmdLines), separator)
+}
+
+// writeScript writes commands into a new executable script in dir and returns path to it
+func writeScript(dir string, cmdLines []string, now time.Time) (string, error) {
+	shell := path.Base(os.Getenv("SHELL"))
+	if shell == "" || shell == "." || shell == "/" {
+		shell = "sh"
+	}
+	fpath := filepath.Join(dir, "resh-script-"+now.Format("20060102-150405")+".sh")
+	content := "#!/usr/bin/env " + shell + "\n" +
+		"# saved from RESH search app on " + now.Format("2006-01-02 15:04:05") + "\n\n" +
+		strings.Join(trimCmdLines(cmdLines), "\n") + "\n"
+
+	f, err := os.OpenFile(fpath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0755)
+	if err != nil {
+		return "", fmt.Errorf("could not create script: %w", err)
+	}
+	_, err = f.WriteString(content)
+	if err != nil {
+		f.Close()
+		return "", fmt.Errorf("could not write script: %w", err)
+	}
+	err = f.Close()
+	if err != nil {
+		return "", fmt.Errorf("could not close script: %w", err)
+	}
+	return fpath, nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/curusarn/resh/internal/cfg"
+)
+
+func TestJoinCmdLines(t *testing.T) {
+	cmdLines := []string{"make build ", "make test\n"}
+	data := map[string]string{
+		cfg.JoinNewline:   "make build\nmake test",
+		cfg.JoinAnd:       "make build && make test",
+		cfg.JoinSemicolon: "make build; make test",
+	}
+	for mode, expected := range data {
+		if joined := joinCmdLines(cmdLines, mode); joined != expected {
+			t.Fatalf("Unexpected result for join mode %s: %q", mode, joined)
+		}
+	}
+	mode := cfg.JoinNewline
+	for range joinModes {
+		mode = nextJoinMode(mode)
+	}
+	if mode != cfg.JoinNewline {
+		t.Fatalf("Expected join modes to cycle, got %s", mode)
+	}
+}
+
+func TestWriteScript(t *testing.T) {
+	t.Setenv("SHELL", "/bin/zsh")
+	dir := t.TempDir()
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.Local)
+	fpath, err := writeScript(dir, []string{"cd project", "make test"}, now)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if fpath != filepath.Join(dir, "resh-script-20240510-120000.sh") {
+		t.Fatalf("Unexpected script path: %s", fpath)
+	}
+	content, err := os.ReadFile(fpath)
+	if err != nil {
+		t.Fatalf("Could not read script: %v", err)
+	}
+	expected := "#!/usr/bin/env zsh\n# saved from RESH search app on 2024-05-10 12:00:00\n\ncd project\nmake test\n"
+	if string(content) != expected {
+		t.Fatalf("Unexpected script content: %q", content)
+	}
+	info, err := os.Stat(fpath)
+	if err != nil || info.Mode().Perm()&0100 == 0 {
+		t.Fatal("Script should be executable")
+	}
+	// existing scripts are never overwritten
+	_, err = writeScript(dir, []string{"ls"}, now)
+	if err == nil {
+		t.Fatal("Expected error for existing script")
+	}
+}
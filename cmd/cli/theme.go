@@ -0,0 +1,21 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	"github.com/curusarn/resh/internal/cfg"
+)
+
+// getTheme returns theme from config unless colors are disabled
+// Colors are disabled by NO_COLOR (https://no-color.org) and on monochrome terminals (e.g. TERM=vt100-m)
+func getTheme(config cfg.Config) cfg.Theme {
+	if os.Getenv("NO_COLOR") != "" {
+		return cfg.Themes[cfg.ThemeNoColor]
+	}
+	term := os.Getenv("TERM")
+	if term == "dumb" || strings.HasSuffix(term, "-m") || strings.HasSuffix(term, "-mono") {
+		return cfg.Themes[cfg.ThemeNoColor]
+	}
+	return config.Theme
+}
@@ -6,10 +6,13 @@ import (
 
 	"github.com/curusarn/resh/internal/cfg"
 	"github.com/curusarn/resh/internal/collect"
+	"github.com/curusarn/resh/internal/envinfo"
+	"github.com/curusarn/resh/internal/gitinfo"
 	"github.com/curusarn/resh/internal/logger"
 	"github.com/curusarn/resh/internal/opt"
 	"github.com/curusarn/resh/internal/output"
 	"github.com/curusarn/resh/internal/recordint"
+	"github.com/curusarn/resh/internal/redact"
 	"github.com/curusarn/resh/record"
 	"github.com/spf13/pflag"
 	"go.uber.org/zap"
@@ -50,6 +53,12 @@ func main() {
 	timeStr := flags.String("time", "-1", "$EPOCHREALTIME")
 	flags.Parse(args)
 
+	if redact.Ignored(*cmdLine, config.IgnoreSpace, config.HistIgnore) {
+		// daemon drops the second part of the record because there is nothing to merge it with
+		out.Logger.Debug("Ignoring command")
+		return
+	}
+
 	time, err := strconv.ParseFloat(*timeStr, 64)
 	if err != nil {
 		out.FatalE("Error while parsing flag --time", err)
@@ -60,6 +69,7 @@ func main() {
 		out.ErrorE("Error while evaluating symlinks in PWD", err)
 		realPwd = ""
 	}
+	git := gitinfo.Get(*pwd, config.GitDirty)
 
 	rec := recordint.Collect{
 		SessionID:  *sessionID,
@@ -67,8 +77,14 @@ func main() {
 		SessionPID: *sessionPID,
 
 		Shell: *shell,
+		Terminal: recordint.Terminal{
+			WindowID: os.Getenv("WINDOWID"),
+			Display:  os.Getenv("DISPLAY"),
+			Tmux:     os.Getenv("TMUX"),
+			TmuxPane: os.Getenv("TMUX_PANE"),
+		},
 
-		Rec: record.V1{
+		Rec: record.V2{
 			SessionID: *sessionID,
 			RecordID:  *recordID,
 
@@ -80,6 +96,12 @@ func main() {
 			RealPwd: realPwd,
 
 			GitOriginRemote: *gitRemote,
+			GitBranch:       git.Branch,
+			GitCommit:       git.Commit,
+			GitToplevel:     git.Toplevel,
+			GitDirty:        git.Dirty,
+
+			Env: envinfo.Get(config.CaptureEnv),
 
 			Time: fmt.Sprintf("%.4f", time),
 
@@ -87,5 +109,5 @@ func main() {
 			PartsNotMerged: true,
 		},
 	}
-	collect.SendRecord(out, rec, strconv.Itoa(config.Port), "/record")
+	collect.SendRecord(out, rec, config, "/record")
 }
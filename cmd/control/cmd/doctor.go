@@ -14,9 +14,13 @@ import (
 	"go.uber.org/zap"
 )
 
-func doctorCmdFunc(config cfg.Config) func(*cobra.Command, []string) {
+func doctorCmdFunc(config cfg.Config, errCfg error) func(*cobra.Command, []string) {
 	return func(cmd *cobra.Command, args []string) {
 		allOK := true
+		if !checkConfig(errCfg) {
+			allOK = false
+			printDivider()
+		}
 		if !checkDaemon(config) {
 			allOK = false
 			printDivider()
@@ -40,6 +44,24 @@ func printDivider() {
 	fmt.Printf("\n")
 }
 
+var msgConfigProblem = `There is a problem with your RESH config - defaults are used for invalid options
+ -> %v
+ -> Fix your config: %s
+ -> Documentation of all options is at the top of the config file
+`
+
+func checkConfig(errCfg error) bool {
+	if errCfg == nil {
+		return true
+	}
+	configPath, err := cfg.GetPath()
+	if err != nil {
+		configPath = "~/.config/resh.toml"
+	}
+	out.InfoE(fmt.Sprintf(msgConfigProblem, errCfg, configPath), errCfg)
+	return false
+}
+
 var msgFailedDaemonStart = `Failed to start RESH daemon.
  -> Start RESH daemon manually - run: resh-daemon-start
  -> Or restart this terminal window to bring RESH daemon back up
@@ -49,11 +71,11 @@ var msgFailedDaemonStart = `Failed to start RESH daemon.
 
 func checkDaemon(config cfg.Config) bool {
 	ok := true
-	resp, err := status.GetDaemonStatus(config.Port)
+	resp, err := status.GetDaemonStatus(config)
 	if err != nil {
 		out.InfoE("RESH Daemon is not running", err)
 		out.Info("Attempting to start RESH daemon ...")
-		resp, err = startDaemon(config.Port, 5, 200*time.Millisecond)
+		resp, err = startDaemon(config, 5, 200*time.Millisecond)
 		if err != nil {
 			out.InfoE(msgFailedDaemonStart, err)
 			return false
@@ -65,10 +87,21 @@ func checkDaemon(config cfg.Config) bool {
 		out.InfoDaemonVersionMismatch(version, resp.Version)
 		return false
 	}
+	for _, q := range resp.Queues {
+		if q.Dropped > 0 {
+			out.Info(fmt.Sprintf(msgDroppedEvents, q.Dropped, q.Subscriber, q.Bus))
+			ok = false
+		}
+	}
 	return ok
 }
 
-func startDaemon(port int, maxRetries int, backoff time.Duration) (*msg.StatusResponse, error) {
+var msgDroppedEvents = `RESH daemon dropped %d events because '%s' couldn't keep up with '%s' queue
+ -> If it's a plugin, check that it reads its input fast enough
+ -> You can check logs: ~/.local/share/resh/log.json (or ~/$XDG_DATA_HOME/resh/log.json)
+`
+
+func startDaemon(config cfg.Config, maxRetries int, backoff time.Duration) (*msg.StatusResponse, error) {
 	err := exec.Command("resh-daemon-start").Run()
 	if err != nil {
 		return nil, err
@@ -77,7 +110,7 @@ func startDaemon(port int, maxRetries int, backoff time.Duration) (*msg.StatusRe
 	retry := 0
 	for {
 		time.Sleep(backoff)
-		resp, err = status.GetDaemonStatus(port)
+		resp, err = status.GetDaemonStatus(config)
 		if err == nil {
 			break
 		}
@@ -143,5 +176,15 @@ func checkShells() bool {
 		allOK = false
 	}
 
+	msg, err = check.FishVersion()
+	if err != nil {
+		out.InfoE("Failed to check fish version", err)
+		allOK = false
+	}
+	if msg != "" {
+		out.Info(msg)
+		allOK = false
+	}
+
 	return allOK
 }
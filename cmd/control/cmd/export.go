@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/curusarn/resh/internal/datadir"
+	"github.com/curusarn/resh/internal/device"
+	"github.com/curusarn/resh/internal/histio"
+	"github.com/curusarn/resh/internal/recfmt"
+	"github.com/curusarn/resh/internal/recio"
+	"github.com/curusarn/resh/record"
+	"github.com/spf13/cobra"
+)
+
+// options of the export command
+var exportOpts struct {
+	format     string
+	output     string
+	allDevices bool
+}
+
+func exportCmdFunc(cmd *cobra.Command, args []string) {
+	format, err := recfmt.ParseFormat(exportOpts.format)
+	if err != nil {
+		out.FatalE("Invalid export format", err)
+	}
+	recs := readHistory(exportOpts.allDevices)
+
+	var w io.Writer = os.Stdout
+	if exportOpts.output != "" && exportOpts.output != "-" {
+		file, err := os.Create(exportOpts.output)
+		if err != nil {
+			out.FatalE("Could not create output file", err)
+		}
+		defer file.Close()
+		w = file
+	}
+	count, err := recfmt.Write(w, format, recs)
+	if err != nil {
+		out.FatalE("Failed to export history", err)
+	}
+	if count < len(recs) {
+		out.Error(fmt.Sprintf("Skipped %d records that can't be exported in '%s' format", len(recs)-count, format))
+	}
+}
+
+// readHistory returns records from history of this device or all devices sorted by time - deleted records are skipped
+func readHistory(allDevices bool) []record.V2 {
+	sugar := out.Logger.Sugar()
+	dataDir, err := datadir.GetPath()
+	if err != nil {
+		out.FatalE("Could not get user data directory", err)
+	}
+	var paths []string
+	if allDevices {
+		paths, err = histio.GetPaths(dataDir)
+		if err != nil {
+			out.FatalE("Could not list history files", err)
+		}
+	} else {
+		deviceID, err := device.GetID(dataDir)
+		if err != nil {
+			out.FatalE("Could not get device ID", err)
+		}
+		paths = []string{histio.GetPath(dataDir, deviceID)}
+	}
+
+	rio := recio.New(sugar)
+	var recs []record.V2
+	for _, fpath := range paths {
+		fileRecs, decodeErrs, err := rio.ReadFile(fpath)
+		if err != nil {
+			out.FatalE("Could not read history file", err)
+		}
+		if len(decodeErrs) > 0 {
+			out.Error(fmt.Sprintf("Skipped %d invalid lines in history file '%s'", len(decodeErrs), fpath))
+		}
+		for _, rec := range fileRecs {
+			if rec.Deleted {
+				continue
+			}
+			recs = append(recs, rec)
+		}
+	}
+	if len(paths) > 1 {
+		sort.SliceStable(recs, func(i, j int) bool {
+			ti, _ := strconv.ParseFloat(recs[i].Time, 64)
+			tj, _ := strconv.ParseFloat(recs[j].Time, 64)
+			return ti < tj
+		})
+	}
+	return recs
+}
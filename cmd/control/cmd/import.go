@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/curusarn/resh/internal/cfg"
+	"github.com/curusarn/resh/internal/datadir"
+	"github.com/curusarn/resh/internal/device"
+	"github.com/curusarn/resh/internal/histio"
+	"github.com/curusarn/resh/internal/recfmt"
+	"github.com/curusarn/resh/internal/recio"
+	"github.com/curusarn/resh/internal/status"
+	"github.com/curusarn/resh/record"
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+)
+
+// options of the import command
+var importOpts struct {
+	format string
+}
+
+var errDaemonRunning = errors.New("stop it first - run: resh-daemon-stop")
+
+func importCmdFunc(config cfg.Config) func(*cobra.Command, []string) {
+	return func(cmd *cobra.Command, args []string) {
+		sugar := out.Logger.Sugar()
+		// daemon appends to the same history file and keeps its own copy of it
+		if _, err := status.GetDaemonStatus(config); err == nil {
+			out.FatalE("Can't import history while RESH daemon is running", errDaemonRunning)
+		}
+		format, err := recfmt.ParseFormat(importOpts.format)
+		if err != nil {
+			out.FatalE("Invalid import format", err)
+		}
+		dataDir, err := datadir.GetPath()
+		if err != nil {
+			out.FatalE("Could not get user data directory", err)
+		}
+		deviceID, err := device.GetID(dataDir)
+		if err != nil {
+			out.FatalE("Could not get device ID", err)
+		}
+		deviceName, err := device.GetName(dataDir)
+		if err != nil {
+			out.FatalE("Could not get device name", err)
+		}
+
+		file, err := os.Open(args[0])
+		if err != nil {
+			out.FatalE("Could not open file", err)
+		}
+		defer file.Close()
+		recs, skipped, err := recfmt.Read(file, format)
+		if err != nil {
+			out.FatalE("Could not read file", err)
+		}
+
+		rio := recio.New(sugar)
+		paths, err := histio.GetPaths(dataDir)
+		if err != nil {
+			out.FatalE("Could not list history files", err)
+		}
+		// don't import commands that are already in history
+		known := map[string]bool{}
+		for _, fpath := range paths {
+			existing, _, err := rio.ReadFile(fpath)
+			if err != nil {
+				out.FatalE("Could not read history file", err)
+			}
+			for _, rec := range existing {
+				known[importKey(rec)] = true
+			}
+		}
+
+		sessionID, err := uuid.NewRandom()
+		if err != nil {
+			out.FatalE("Could not generate session ID", err)
+		}
+		var toImport []record.V2
+		duplicates := 0
+		for _, rec := range recs {
+			key := importKey(rec)
+			if known[key] {
+				duplicates++
+				continue
+			}
+			known[key] = true
+			if rec.RecordID == "" {
+				recordID, err := uuid.NewRandom()
+				if err != nil {
+					out.FatalE("Could not generate record ID", err)
+				}
+				rec.RecordID = recordID.String()
+			}
+			// records are written to history file of this device
+			if rec.DeviceID != deviceID {
+				rec.DeviceID = deviceID
+				rec.Device = deviceName
+			}
+			if rec.SessionID == "" {
+				rec.SessionID = sessionID.String()
+			}
+			toImport = append(toImport, rec)
+		}
+
+		if len(toImport) > 0 {
+			err = rio.AppendToFile(histio.GetPath(dataDir, deviceID), toImport)
+			if err != nil {
+				out.FatalE("Could not write imported records to history", err)
+			}
+		}
+		fmt.Printf("Imported %d records, skipped %d duplicates and %d entries without timestamp\n",
+			len(toImport), duplicates, skipped)
+		if len(toImport) > 0 {
+			fmt.Println("Run 'resh-daemon-start' to make imported records searchable")
+		}
+	}
+}
+
+// importKey identifies the same command executed at the same time
+func importKey(rec record.V2) string {
+	secs, err := strconv.ParseFloat(rec.Time, 64)
+	if err != nil {
+		return rec.Time + " " + rec.CmdLine
+	}
+	return strconv.FormatInt(int64(secs), 10) + " " + rec.CmdLine
+}
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/curusarn/resh/internal/cfg"
+	"github.com/curusarn/resh/internal/datadir"
+	"github.com/curusarn/resh/internal/device"
+	"github.com/curusarn/resh/internal/futil"
+	"github.com/curusarn/resh/internal/histio"
+	"github.com/curusarn/resh/internal/recio"
+	"github.com/curusarn/resh/internal/redact"
+	"github.com/curusarn/resh/internal/status"
+	"github.com/curusarn/resh/record"
+	"github.com/spf13/cobra"
+)
+
+// options of the redact command
+var redactOpts struct {
+	dryRun     bool
+	allDevices bool
+}
+
+func redactCmdFunc(config cfg.Config) func(*cobra.Command, []string) {
+	return func(cmd *cobra.Command, args []string) {
+		// daemon appends to the history file and keeps unredacted records in memory
+		if !redactOpts.dryRun {
+			if _, err := status.GetDaemonStatus(config); err == nil {
+				out.FatalE("Can't redact history while RESH daemon is running", errDaemonRunning)
+			}
+		}
+		redactor, err := redact.New(config.Redaction)
+		if err != nil {
+			out.ErrorE("Some redaction patterns are invalid and will be ignored", err)
+		}
+		dataDir, err := datadir.GetPath()
+		if err != nil {
+			out.FatalE("Could not get user data directory", err)
+		}
+		var paths []string
+		if redactOpts.allDevices {
+			paths, err = histio.GetPaths(dataDir)
+			if err != nil {
+				out.FatalE("Could not list history files", err)
+			}
+		} else {
+			deviceID, err := device.GetID(dataDir)
+			if err != nil {
+				out.FatalE("Could not get device ID", err)
+			}
+			paths = []string{histio.GetPath(dataDir, deviceID)}
+		}
+
+		changed := false
+		for _, fpath := range paths {
+			fileChanged, err := redactHistoryFile(redactor, fpath)
+			if err != nil {
+				out.FatalE("Failed to redact history file", err)
+			}
+			changed = changed || fileChanged
+		}
+		if changed && !redactOpts.dryRun {
+			fmt.Println("Run 'resh-daemon-start' to search the redacted history")
+		}
+	}
+}
+
+// redactHistoryFile rewrites history file in place
+// Returns true if any records were changed
+func redactHistoryFile(redactor *redact.Redactor, fpath string) (bool, error) {
+	rio := recio.New(out.Logger.Sugar())
+	recs, decodeErrs, err := rio.ReadFile(fpath)
+	if err != nil {
+		return false, fmt.Errorf("could not read history file: %w", err)
+	}
+	if len(decodeErrs) > 0 {
+		// rewriting the file would drop the invalid lines
+		return false, fmt.Errorf("history file '%s' contains %d invalid lines - fix or remove them first", fpath, len(decodeErrs))
+	}
+
+	var redacted []record.V2
+	masked, dropped := 0, 0
+	for _, rec := range recs {
+		cmdLine, keep := redactor.CmdLine(rec.CmdLine)
+		if !keep {
+			dropped++
+			continue
+		}
+		if cmdLine != rec.CmdLine {
+			masked++
+			rec.CmdLine = cmdLine
+		}
+		redacted = append(redacted, rec)
+	}
+	fmt.Printf("%s: %d records masked, %d records dropped\n", fpath, masked, dropped)
+	if masked == 0 && dropped == 0 {
+		return false, nil
+	}
+	if redactOpts.dryRun {
+		return true, nil
+	}
+
+	backup, err := futil.BackupFile(fpath)
+	if err != nil {
+		return false, fmt.Errorf("could not back up history file: %w", err)
+	}
+	err = rio.OverwriteFile(fpath, redacted)
+	if err != nil {
+		errRestore := backup.Restore()
+		if errRestore != nil {
+			out.ErrorE("FAILED TO RESTORE RESH HISTORY FROM BACKUP!", errRestore)
+			fmt.Printf(" -> Backup is '%s'\n", backup.PathBackup)
+		}
+		return false, fmt.Errorf("could not write history file: %w", err)
+	}
+	fmt.Printf(" -> Backup is '%s' - delete it once you check the redacted history because it still contains the secrets\n", backup.PathBackup)
+	return true, nil
+}
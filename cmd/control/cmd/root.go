@@ -17,7 +17,7 @@ var out *output.Output
 
 var rootCmd = &cobra.Command{
 	Use:   "reshctl",
-	Short: "Reshctl (RESH control) - check status, update",
+	Short: "Reshctl (RESH control) - check status, update, search, export, import and analyze history",
 }
 
 // Execute reshctl
@@ -46,10 +46,79 @@ func Execute(ver, com, development string) {
 	doctorCmd := cobra.Command{
 		Use:   "doctor",
 		Short: "check common problems",
-		Run:   doctorCmdFunc(config),
+		Run:   doctorCmdFunc(config, errCfg),
 	}
 	rootCmd.AddCommand(&doctorCmd)
 
+	searchCmd := cobra.Command{
+		Use:   "search [QUERY]",
+		Short: "search history and print the best results",
+		Long: "Search RESH history without the interactive search app.\n" +
+			"Results are ranked the same way as in the search app based on the current directory and git repository.",
+		Run: searchCmdFunc(config),
+	}
+	searchCmd.Flags().StringVar(&searchOpts.format, "format", "plain", "Output format: plain, tsv, json")
+	searchCmd.Flags().IntVarP(&searchOpts.limit, "limit", "n", 20, "Maximum number of results (0 for no limit)")
+	searchCmd.Flags().StringVar(&searchOpts.pwd, "pwd", "", "Only show commands executed in this directory")
+	searchCmd.Flags().StringVar(&searchOpts.gitRemote, "git-remote", "", "Only show commands executed in this git repository (origin remote)")
+	searchCmd.Flags().StringVar(&searchOpts.device, "device", "", "Only show commands executed on this device")
+	searchCmd.Flags().IntVar(&searchOpts.exitCode, "exit-code", 0, "Only show commands with this exit code")
+	searchCmd.Flags().StringVar(&searchOpts.since, "since", "", "Only show commands executed after this time (e.g. 2024-01-31, '2024-01-31 14:00', 3d)")
+	searchCmd.Flags().StringVar(&searchOpts.until, "until", "", "Only show commands executed before this time (e.g. 2024-01-31, '2024-01-31 14:00', 3d)")
+	searchCmd.Flags().BoolVar(&searchOpts.fuzzy, "fuzzy", config.Search.Matching == cfg.MatchingFuzzy, "Match query terms as subsequences (e.g. 'gco' matches 'git checkout')")
+	rootCmd.AddCommand(&searchCmd)
+
+	exportCmd := cobra.Command{
+		Use:   "export",
+		Short: "export history to other formats",
+		Long: "Export RESH history as JSON Lines, CSV, bash history with timestamps or zsh extended history.\n" +
+			"Only history of this device is exported by default.",
+		Args: cobra.NoArgs,
+		Run:  exportCmdFunc,
+	}
+	exportCmd.Flags().StringVar(&exportOpts.format, "format", "jsonl", "Output format: jsonl, csv, bash, zsh")
+	exportCmd.Flags().StringVarP(&exportOpts.output, "output", "o", "", "Output file (default stdout)")
+	exportCmd.Flags().BoolVar(&exportOpts.allDevices, "all-devices", false, "Export history of all devices")
+	rootCmd.AddCommand(&exportCmd)
+
+	statsCmd := cobra.Command{
+		Use:   "stats",
+		Short: "show statistics of recorded history",
+		Long: "Show most used and slowest commands, failure rates, activity by hour and weekday,\n" +
+			"top directories and git repositories and breakdown by device.\n" +
+			"History of all devices is analyzed by default.",
+		Args: cobra.NoArgs,
+		Run:  statsCmdFunc,
+	}
+	statsCmd.Flags().BoolVar(&statsOpts.json, "json", false, "Output stats as JSON")
+	statsCmd.Flags().IntVarP(&statsOpts.top, "top", "n", 10, "Number of items in each list (0 for no limit)")
+	statsCmd.Flags().StringVar(&statsOpts.since, "since", "", "Only analyze commands executed after this time (e.g. 2024-01-31, 30d)")
+	statsCmd.Flags().BoolVar(&statsOpts.thisDevice, "this-device", false, "Only analyze history of this device")
+	rootCmd.AddCommand(&statsCmd)
+
+	importCmd := cobra.Command{
+		Use:   "import FILE",
+		Short: "import history from other formats",
+		Long: "Import history from JSON Lines, CSV, bash history with timestamps (HISTTIMEFORMAT) or zsh extended history (EXTENDED_HISTORY).\n" +
+			"Commands already present in RESH history are skipped. RESH daemon has to be stopped during import.",
+		Args: cobra.ExactArgs(1),
+		Run:  importCmdFunc(config),
+	}
+	importCmd.Flags().StringVar(&importOpts.format, "format", "jsonl", "Input format: jsonl, csv, bash, zsh")
+	rootCmd.AddCommand(&importCmd)
+
+	redactCmd := cobra.Command{
+		Use:   "redact",
+		Short: "remove secrets from recorded history",
+		Long: "Apply redaction rules from the config to already recorded history.\n" +
+			"History file is rewritten in place - a backup is created first. RESH daemon has to be stopped unless --dry-run is used.",
+		Args: cobra.NoArgs,
+		Run:  redactCmdFunc(config),
+	}
+	redactCmd.Flags().BoolVar(&redactOpts.dryRun, "dry-run", false, "Only show how many records would be changed")
+	redactCmd.Flags().BoolVar(&redactOpts.allDevices, "all-devices", false, "Redact history of all devices")
+	rootCmd.AddCommand(&redactCmd)
+
 	updateCmd.Flags().BoolVar(&betaFlag, "beta", false, "Update to latest version even if it's beta.")
 	rootCmd.AddCommand(updateCmd)
 
@@ -0,0 +1,227 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/curusarn/resh/internal/cfg"
+	"github.com/curusarn/resh/internal/cli"
+	"github.com/curusarn/resh/internal/envinfo"
+	"github.com/curusarn/resh/internal/gitinfo"
+	"github.com/curusarn/resh/internal/msg"
+	"github.com/curusarn/resh/internal/normalize"
+	"github.com/curusarn/resh/internal/recordint"
+	"github.com/curusarn/resh/internal/searchapp"
+	"github.com/spf13/cobra"
+)
+
+// options of the search command
+var searchOpts struct {
+	format    string
+	limit     int
+	pwd       string
+	gitRemote string
+	device    string
+	exitCode  int
+	since     string
+	until     string
+	fuzzy     bool
+}
+
+var searchFormats = map[string]bool{
+	"plain": true,
+	"tsv":   true,
+	"json":  true,
+}
+
+// searchResult is the output of the search command
+type searchResult struct {
+	CmdLine         string            `json:"cmdLine"`
+	Time            string            `json:"time,omitempty"`
+	Device          string            `json:"device,omitempty"`
+	Pwd             string            `json:"pwd,omitempty"`
+	GitOriginRemote string            `json:"gitOriginRemote,omitempty"`
+	GitBranch       string            `json:"gitBranch,omitempty"`
+	Env             map[string]string `json:"env,omitempty"`
+	ExitCode        int               `json:"exitCode"`
+	SessionID       string            `json:"sessionID,omitempty"`
+	RecordID        string            `json:"recordID,omitempty"`
+	Score           float64           `json:"score"`
+	// stats of all executions of the command
+	Count     int    `json:"count"`
+	FirstSeen string `json:"firstSeen,omitempty"`
+	LastSeen  string `json:"lastSeen,omitempty"`
+}
+
+func searchCmdFunc(config cfg.Config) func(*cobra.Command, []string) {
+	return func(cmd *cobra.Command, args []string) {
+		if !searchFormats[searchOpts.format] {
+			out.FatalE("Invalid search format", fmt.Errorf("unknown format '%s' - use one of: plain, tsv, json", searchOpts.format))
+		}
+		filter, err := getSearchFilter(cmd)
+		if err != nil {
+			out.FatalE("Invalid search filter", err)
+		}
+		pwd, err := os.Getwd()
+		if err != nil {
+			out.FatalE("Could not get working directory", err)
+		}
+		// git remote is only used for ranking - no remote is fine
+		gitRemote, _ := exec.Command("git", "remote", "get-url", "origin").Output()
+		gitBranch := gitinfo.Branch(pwd)
+
+		mess := msg.SearchMsg{
+			SessionID:       os.Getenv("__RESH_SESSION_ID"),
+			Query:           strings.Join(args, " "),
+			PWD:             pwd,
+			GitOriginRemote: strings.TrimSpace(string(gitRemote)),
+			GitBranch:       gitBranch,
+			Env:             envinfo.Get(config.CaptureEnv),
+			Fuzzy:           searchOpts.fuzzy,
+			Options: searchapp.Options{
+				Filter:       filter,
+				OnlyMatching: true,
+				Limit:        searchOpts.limit,
+			},
+		}
+		found, err := cli.Search(context.Background(), mess, config)
+		if err != nil {
+			out.FatalDaemonNotRunning(err)
+		}
+		results := make([]searchResult, 0, len(found))
+		for _, res := range found {
+			results = append(results, newSearchResult(res.Record, res.Score, res.Stats))
+		}
+
+		err = printSearchResults(results, searchOpts.format)
+		if err != nil {
+			out.FatalE("Failed to print search results", err)
+		}
+	}
+}
+
+func getSearchFilter(cmd *cobra.Command) (searchapp.Filter, error) {
+	sugar := out.Logger.Sugar()
+	pwd, err := normalizePwd(searchOpts.pwd)
+	if err != nil {
+		return searchapp.Filter{}, fmt.Errorf("could not get absolute path of --pwd: %w", err)
+	}
+	filter := searchapp.Filter{
+		Pwd:             pwd,
+		GitOriginRemote: normalize.GitRemote(sugar, searchOpts.gitRemote),
+		Host:            searchOpts.device,
+	}
+	if cmd.Flags().Changed("exit-code") {
+		filter.ExitCode = &searchOpts.exitCode
+	}
+	now := time.Now()
+	if searchOpts.since != "" {
+		filter.Since, err = searchapp.ParseTime(searchOpts.since, now)
+		if err != nil {
+			return filter, fmt.Errorf("could not parse --since: %w", err)
+		}
+	}
+	if searchOpts.until != "" {
+		filter.Until, err = searchapp.ParseTime(searchOpts.until, now)
+		if err != nil {
+			return filter, fmt.Errorf("could not parse --until: %w", err)
+		}
+	}
+	return filter, nil
+}
+
+// normalizePwd returns clean absolute path - recorded directories are absolute
+func normalizePwd(pwd string) (string, error) {
+	if pwd == "" {
+		return "", nil
+	}
+	// '~' is not expanded by shell when quoted
+	if pwd == "~" || strings.HasPrefix(pwd, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("could not get user home dir: %w", err)
+		}
+		pwd = home + pwd[1:]
+	}
+	// also cleans the path
+	return filepath.Abs(pwd)
+}
+
+func formatTime(t float64) string {
+	secs := int64(t)
+	nsecs := int64((t - float64(secs)) * 1e9)
+	return time.Unix(secs, nsecs).Format(time.RFC3339)
+}
+
+func newSearchResult(rec recordint.SearchApp, score float64, stats searchapp.Stats) searchResult {
+	res := searchResult{
+		CmdLine:         rec.CmdLine,
+		Device:          rec.Host,
+		Pwd:             rec.Pwd,
+		GitOriginRemote: rec.GitOriginRemote,
+		GitBranch:       rec.GitBranch,
+		Env:             rec.Env,
+		ExitCode:        rec.ExitCode,
+		SessionID:       rec.SessionID,
+		RecordID:        rec.RecordID,
+		Score:           score,
+		Count:           stats.Count,
+	}
+	if !rec.IsRaw {
+		res.Time = formatTime(rec.Time)
+	}
+	if stats.FirstSeen != 0 {
+		res.FirstSeen = formatTime(stats.FirstSeen)
+		res.LastSeen = formatTime(stats.LastSeen)
+	}
+	return res
+}
+
+func printSearchResults(results []searchResult, format string) error {
+	switch format {
+	case "plain":
+		for _, res := range results {
+			fmt.Println(res.CmdLine)
+		}
+	case "tsv":
+		for _, res := range results {
+			fields := []string{
+				res.Time,
+				res.Device,
+				res.Pwd,
+				res.GitOriginRemote,
+				strconv.Itoa(res.ExitCode),
+				res.CmdLine,
+			}
+			for i, field := range fields {
+				fields[i] = escapeTSV(field)
+			}
+			fmt.Println(strings.Join(fields, "\t"))
+		}
+	case "json":
+		if results == nil {
+			results = []searchResult{}
+		}
+		jsn, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode json: %w", err)
+		}
+		fmt.Println(string(jsn))
+	default:
+		return fmt.Errorf("unknown format '%s' - use one of: plain, tsv, json", format)
+	}
+	return nil
+}
+
+func escapeTSV(str string) string {
+	str = strings.ReplaceAll(str, "\\", "\\\\")
+	str = strings.ReplaceAll(str, "\t", "\\t")
+	return strings.ReplaceAll(str, "\n", "\\n")
+}
@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/curusarn/resh/internal/histstats"
+	"github.com/curusarn/resh/internal/searchapp"
+	"github.com/curusarn/resh/record"
+	"github.com/spf13/cobra"
+)
+
+// options of the stats command
+var statsOpts struct {
+	json       bool
+	top        int
+	since      string
+	thisDevice bool
+}
+
+// width of the longest bar in activity histograms
+const statsBarWidth = 40
+
+func statsCmdFunc(cmd *cobra.Command, args []string) {
+	var since time.Time
+	if statsOpts.since != "" {
+		var err error
+		since, err = searchapp.ParseTime(statsOpts.since, time.Now())
+		if err != nil {
+			out.FatalE("Could not parse --since", err)
+		}
+	}
+	recs := readHistory(!statsOpts.thisDevice)
+	if !since.IsZero() {
+		recs = recordsSince(recs, since)
+	}
+	stats := histstats.New(recs, statsOpts.top)
+
+	if statsOpts.json {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(stats); err != nil {
+			out.FatalE("Could not encode stats", err)
+		}
+		return
+	}
+	if err := printStats(os.Stdout, stats); err != nil {
+		out.FatalE("Could not print stats", err)
+	}
+}
+
+func recordsSince(recs []record.V2, since time.Time) []record.V2 {
+	var result []record.V2
+	for _, rec := range recs {
+		t, err := strconv.ParseFloat(rec.Time, 64)
+		if err == nil && t >= float64(since.Unix()) {
+			result = append(result, rec)
+		}
+	}
+	return result
+}
+
+func printStats(w io.Writer, stats histstats.Stats) error {
+	if stats.RecordCount == 0 {
+		_, err := fmt.Fprintln(w, "No history recorded yet")
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	const layout = "2006-01-02 15:04"
+	fmt.Fprintf(tw, "Commands:\t%d\n", stats.RecordCount)
+	fmt.Fprintf(tw, "First:\t%s\n", stats.First.Local().Format(layout))
+	fmt.Fprintf(tw, "Last:\t%s\n", stats.Last.Local().Format(layout))
+
+	section(tw, "Most used commands", "COUNT\tFAILED\tCOMMAND")
+	for _, c := range stats.Commands {
+		fmt.Fprintf(tw, "%d\t%s\t%s\n", c.Count, percent(c.FailureRate), c.Name)
+	}
+	section(tw, "Most used executables", "COUNT\tFAILED\tAVG TIME\tEXECUTABLE")
+	for _, c := range stats.Executables {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", c.Count, percent(c.FailureRate), formatDuration(c.AvgDuration), c.Name)
+	}
+	section(tw, "Most failing executables", "FAILED\tRUNS\tEXECUTABLE")
+	for _, c := range stats.Failing {
+		fmt.Fprintf(tw, "%s\t%d\t%s\n", percent(c.FailureRate), c.Count, c.Name)
+	}
+	section(tw, "Slowest commands", "TIME\tEXIT\tDATE\tCOMMAND")
+	for _, s := range stats.Slowest {
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\n", formatDuration(s.Duration), s.ExitCode, s.Time.Local().Format(layout), s.CmdLine)
+	}
+
+	section(tw, "Activity by hour", "")
+	hours := stats.Hours[:]
+	for hour, count := range hours {
+		fmt.Fprintf(tw, "%02d\t%d\t%s\n", hour, count, bar(count, hours))
+	}
+	section(tw, "Activity by weekday", "")
+	// weeks start on Monday like in the search app time filter
+	weekdays := stats.Weekdays[:]
+	for i := 1; i <= 7; i++ {
+		day := time.Weekday(i % 7)
+		fmt.Fprintf(tw, "%s\t%d\t%s\n", day.String()[:3], weekdays[day], bar(weekdays[day], weekdays))
+	}
+
+	section(tw, "Top directories", "COUNT\tDIRECTORY")
+	for _, c := range stats.Directories {
+		fmt.Fprintf(tw, "%d\t%s\n", c.Count, c.Name)
+	}
+	section(tw, "Top git repositories", "COUNT\tREPOSITORY")
+	for _, c := range stats.GitRepos {
+		fmt.Fprintf(tw, "%d\t%s\n", c.Count, c.Name)
+	}
+	section(tw, "Devices", "COUNT\tFAILED\tDIRS\tFIRST\tLAST\tDEVICE")
+	for _, d := range stats.Devices {
+		fmt.Fprintf(tw, "%d\t%s\t%d\t%s\t%s\t%s\n", d.Count, percent(float64(d.Failed)/float64(d.Count)), d.Directories,
+			d.First.Local().Format(layout), d.Last.Local().Format(layout), d.Name)
+	}
+	return tw.Flush()
+}
+
+func section(w io.Writer, title, header string) {
+	fmt.Fprintf(w, "\n%s:\n", title)
+	if header != "" {
+		fmt.Fprintln(w, header)
+	}
+}
+
+func percent(rate float64) string {
+	return fmt.Sprintf("%.0f%%", rate*100)
+}
+
+func formatDuration(secs float64) string {
+	d := time.Duration(secs * float64(time.Second))
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(time.Second).String()
+}
+
+func bar(count int, counts []int) string {
+	max := 0
+	for _, c := range counts {
+		if c > max {
+			max = c
+		}
+	}
+	if max == 0 {
+		return ""
+	}
+	return strings.Repeat("#", count*statsBarWidth/max)
+}
@@ -17,7 +17,7 @@ func versionCmdFunc(config cfg.Config) func(*cobra.Command, []string) {
 		versionEnv := getEnvVarWithDefault("__RESH_VERSION", "<unknown>")
 		fmt.Printf("This terminal session: %s\n", version)
 
-		resp, err := status.GetDaemonStatus(config.Port)
+		resp, err := status.GetDaemonStatus(config)
 		if err != nil {
 			fmt.Printf("Running checks: %s\n", version)
 			out.ErrorDaemonNotRunning(err)
@@ -5,14 +5,14 @@ import (
 	"io"
 	"net/http"
 
-	"github.com/curusarn/resh/internal/histfile"
+	"github.com/curusarn/resh/internal/histio"
 	"github.com/curusarn/resh/internal/msg"
 	"go.uber.org/zap"
 )
 
 type dumpHandler struct {
-	sugar       *zap.SugaredLogger
-	histfileBox *histfile.Histfile
+	sugar *zap.SugaredLogger
+	hio   *histio.Histio
 }
 
 func (h *dumpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -35,10 +35,7 @@ func (h *dumpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	sugar.Debugw("Getting records to send ...")
-	fullRecords := h.histfileBox.DumpCliRecords()
-	if err != nil {
-		sugar.Errorw("Error when getting records", "error", err)
-	}
+	fullRecords := h.hio.DumpCliRecords()
 
 	resp := msg.CliResponse{Records: fullRecords.List}
 	jsn, err = json.Marshal(&resp)
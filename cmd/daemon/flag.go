@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/curusarn/resh/internal/histio"
+	"github.com/curusarn/resh/internal/msg"
+	"github.com/curusarn/resh/internal/recordint"
+	"go.uber.org/zap"
+)
+
+type flagHandler struct {
+	sugar *zap.SugaredLogger
+	hio   *histio.Histio
+}
+
+func (h *flagHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	sugar := h.sugar.With(zap.String("endpoint", "/flag"))
+	sugar.Debugw("Handling request, reading body ...")
+	jsn, err := io.ReadAll(r.Body)
+	if err != nil {
+		sugar.Errorw("Error reading body", "error", err)
+		http.Error(w, "could not read body", http.StatusBadRequest)
+		return
+	}
+
+	sugar.Debugw("Unmarshaling flag ...")
+	flag := recordint.Flag{}
+	err = json.Unmarshal(jsn, &flag)
+	if err != nil {
+		sugar.Errorw("Error during unmarshaling",
+			"error", err,
+			"payload", jsn,
+		)
+		http.Error(w, "could not decode flag", http.StatusBadRequest)
+		return
+	}
+	// command line is not logged - records are often deleted because they contain secrets
+	sugar = sugar.With(
+		"flag", flag.Name,
+		"value", flag.Value,
+	)
+	sugar.Debugw("Flagging records ...")
+	count, hidden, err := h.hio.Flag(flag)
+	if err != nil {
+		sugar.Errorw("Error while flagging records", "error", err)
+		http.Error(w, "could not flag records", http.StatusInternalServerError)
+		return
+	}
+
+	resp := msg.FlagResponse{FlaggedCount: count, HiddenCount: hidden}
+	jsn, err = json.Marshal(&resp)
+	if err != nil {
+		sugar.Errorw("Error when marshaling", "error", err)
+		return
+	}
+	w.Write(jsn)
+	sugar.Infow("Request handled", "flaggedCount", count, "hiddenCount", hidden)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path"
+	"strconv"
+
+	"github.com/curusarn/resh/internal/datadir"
+)
+
+// listen on unix socket only accessible by the user
+// or on localhost TCP port when enabled in config
+func (s *Server) listen() (net.Listener, error) {
+	if s.config.UseTCP {
+		s.sugar.Warnw("Listening on TCP port - any local user can connect to the daemon",
+			"port", s.config.Port,
+		)
+		return net.Listen("tcp", "localhost:"+strconv.Itoa(s.config.Port))
+	}
+	socketPath, err := datadir.GetSocketPath()
+	if err != nil {
+		return nil, fmt.Errorf("could not get socket path: %w", err)
+	}
+	// directory permissions make sure nobody else can connect even before we chmod the socket
+	err = os.MkdirAll(path.Dir(socketPath), 0700)
+	if err != nil {
+		return nil, fmt.Errorf("could not create socket directory: %w", err)
+	}
+	err = os.Chmod(path.Dir(socketPath), 0700)
+	if err != nil {
+		return nil, fmt.Errorf("could not set permissions of socket directory: %w", err)
+	}
+	// socket of previous daemon that didn't shut down properly
+	err = os.Remove(socketPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("could not remove stale socket: %w", err)
+	}
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return nil, err
+	}
+	err = os.Chmod(socketPath, 0600)
+	if err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("could not set socket permissions: %w", err)
+	}
+	s.sugar.Infow("Listening on unix socket", "socketPath", socketPath)
+	return listener, nil
+}
@@ -77,9 +77,12 @@ func main() {
 	}
 	// TODO: These paths should be probably defined in a package
 	pidFile := filepath.Join(dataDir, "daemon.pid")
-	reshHistoryPath := filepath.Join(dataDir, datadir.HistoryFileName)
 	bashHistoryPath := filepath.Join(homeDir, ".bash_history")
 	zshHistoryPath := filepath.Join(homeDir, ".zsh_history")
+	fishHistoryPath := filepath.Join(homeDir, ".local/share/fish/fish_history")
+	if xdgDataDir, found := os.LookupEnv("XDG_DATA_HOME"); found {
+		fishHistoryPath = filepath.Join(xdgDataDir, "fish/fish_history")
+	}
 	deviceID, err := device.GetID(dataDir)
 	if err != nil {
 		sugar.Fatalw("Could not get resh device ID", zap.Error(err))
@@ -91,7 +94,7 @@ func main() {
 
 	sugar = sugar.With(zap.Int("daemonPID", os.Getpid()))
 
-	res, err := status.IsDaemonRunning(config.Port)
+	res, err := status.IsDaemonRunning(config)
 	if err != nil {
 		sugar.Errorw("Error while checking daemon status - it's probably not running",
 			"error", err)
@@ -122,9 +125,10 @@ func main() {
 	server := Server{
 		sugar:           sugar,
 		config:          config,
-		reshHistoryPath: reshHistoryPath,
+		dataDir:         dataDir,
 		bashHistoryPath: bashHistoryPath,
 		zshHistoryPath:  zshHistoryPath,
+		fishHistoryPath: fishHistoryPath,
 
 		deviceID:   deviceID,
 		deviceName: deviceName,
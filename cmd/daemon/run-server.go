@@ -3,14 +3,19 @@ package main
 import (
 	"net/http"
 	"os"
-	"strconv"
 	"time"
 
+	"github.com/curusarn/resh/internal/bus"
 	"github.com/curusarn/resh/internal/cfg"
 	"github.com/curusarn/resh/internal/histfile"
+	"github.com/curusarn/resh/internal/histio"
+	"github.com/curusarn/resh/internal/notify"
+	"github.com/curusarn/resh/internal/plugin"
 	"github.com/curusarn/resh/internal/recordint"
+	"github.com/curusarn/resh/internal/redact"
 	"github.com/curusarn/resh/internal/sesswatch"
 	"github.com/curusarn/resh/internal/signalhandler"
+	"github.com/curusarn/resh/record"
 	"go.uber.org/zap"
 )
 
@@ -20,70 +25,115 @@ type Server struct {
 	sugar  *zap.SugaredLogger
 	config cfg.Config
 
-	reshHistoryPath string
+	dataDir         string
 	bashHistoryPath string
 	zshHistoryPath  string
+	fishHistoryPath string
 
 	deviceID   string
 	deviceName string
 }
 
+// sizes of subscriber queues - events are dropped when a subscriber falls this far behind
+// except for histfile which makes publishers wait instead
+const (
+	recordQueueSize  = 1000
+	sessionQueueSize = 100
+)
+
 func (s *Server) Run() {
-	var recordSubscribers []chan recordint.Collect
-	var sessionInitSubscribers []chan recordint.SessionInit
-	var sessionDropSubscribers []chan string
+	records := bus.New[recordint.Collect](s.sugar, "records")
+	sessionInits := bus.New[recordint.SessionInit](s.sugar, "sessionInits")
+	sessionDrops := bus.New[string](s.sugar, "sessionDrops")
+	merged := bus.New[record.V2](s.sugar, "merged")
 	var signalSubscribers []chan os.Signal
 
 	shutdown := make(chan string)
 
+	// histio
+	hio := histio.New(s.sugar, s.dataDir, s.deviceID)
+
+	// redaction
+	redactor, err := redact.New(s.config.Redaction)
+	if err != nil {
+		s.sugar.Errorw("Some redaction patterns are invalid and will be ignored", "error", err)
+	}
+
+	// plugins
+	plugin.Go(s.sugar, s.config.Plugins, merged)
+
 	// histfile
-	histfileRecords := make(chan recordint.Collect)
-	recordSubscribers = append(recordSubscribers, histfileRecords)
-	histfileSessionsToDrop := make(chan string)
-	sessionDropSubscribers = append(sessionDropSubscribers, histfileSessionsToDrop)
 	histfileSignals := make(chan os.Signal)
 	signalSubscribers = append(signalSubscribers, histfileSignals)
 	maxHistSize := 10000  // lines
 	minHistSizeKB := 2000 // roughly lines
-	histfileBox := histfile.New(s.sugar, histfileRecords, histfileSessionsToDrop,
-		s.reshHistoryPath, s.bashHistoryPath, s.zshHistoryPath,
+	// history must not lose records - publishing waits when histfile can't keep up
+	histfile.New(s.sugar,
+		records.SubscribeLossless("histfile", recordQueueSize),
+		sessionDrops.SubscribeLossless("histfile", sessionQueueSize),
+		merged,
+		hio, redactor, s.bashHistoryPath, s.zshHistoryPath, s.fishHistoryPath,
 		maxHistSize, minHistSizeKB,
 		histfileSignals, shutdown)
 
+	// notify
+	if s.config.Notify.Enabled {
+		notify.Go(s.sugar, s.config.Notify,
+			records.Subscribe("notify", recordQueueSize),
+			sessionDrops.Subscribe("notify", sessionQueueSize),
+			notify.New(s.config.Notify), notify.SystemFocus{}, redactor)
+	}
+
 	// sesswatch
-	sesswatchRecords := make(chan recordint.Collect)
-	recordSubscribers = append(recordSubscribers, sesswatchRecords)
-	sesswatchSessionsToWatch := make(chan recordint.SessionInit)
-	sessionInitSubscribers = append(sessionInitSubscribers, sesswatchSessionsToWatch)
 	sesswatch.Go(
 		s.sugar,
-		sesswatchSessionsToWatch,
-		sesswatchRecords,
-		sessionDropSubscribers,
+		sessionInits.Subscribe("sesswatch", sessionQueueSize),
+		records.Subscribe("sesswatch", recordQueueSize),
+		sessionDrops,
 		s.config.SessionWatchPeriodSeconds,
 	)
 
 	// handlers
 	mux := http.NewServeMux()
-	mux.Handle("/status", &statusHandler{sugar: s.sugar})
+	mux.Handle("/status", &statusHandler{
+		sugar: s.sugar,
+		queueStats: func() []bus.Stats {
+			stats := records.Stats()
+			stats = append(stats, sessionInits.Stats()...)
+			stats = append(stats, sessionDrops.Stats()...)
+			return append(stats, merged.Stats()...)
+		},
+	})
 	mux.Handle("/record", &recordHandler{
-		sugar:       s.sugar,
-		subscribers: recordSubscribers,
-		deviceID:    s.deviceID,
-		deviceName:  s.deviceName,
+		sugar:      s.sugar,
+		records:    records,
+		deviceID:   s.deviceID,
+		deviceName: s.deviceName,
+	})
+	mux.Handle("/session_init", &sessionInitHandler{sugar: s.sugar, sessionInits: sessionInits})
+	mux.Handle("/dump", &dumpHandler{sugar: s.sugar, hio: hio})
+	mux.Handle("/flag", &flagHandler{sugar: s.sugar, hio: hio})
+	mux.Handle("/session", &sessionHandler{sugar: s.sugar, hio: hio})
+	mux.Handle("/search", &searchHandler{
+		sugar:      s.sugar,
+		hio:        hio,
+		deviceName: s.deviceName,
+		ranking:    s.config.Search.Ranking,
+		debug:      s.config.Debug,
 	})
-	mux.Handle("/session_init", &sessionInitHandler{sugar: s.sugar, subscribers: sessionInitSubscribers})
-	mux.Handle("/dump", &dumpHandler{sugar: s.sugar, histfileBox: histfileBox})
 
+	listener, err := s.listen()
+	if err != nil {
+		s.sugar.Fatalw("Could not listen", "error", err)
+	}
 	server := &http.Server{
-		Addr:              "localhost:" + strconv.Itoa(s.config.Port),
 		Handler:           mux,
 		ReadTimeout:       1 * time.Second,
-		WriteTimeout:      1 * time.Second,
+		WriteTimeout:      30 * time.Second, // dumps of large histories can take a while
 		ReadHeaderTimeout: 1 * time.Second,
 		IdleTimeout:       30 * time.Second,
 	}
-	go server.ListenAndServe()
+	go server.Serve(listener)
 
 	// signalhandler - takes over the main goroutine so when signal handler exists the whole program exits
 	signalhandler.Run(s.sugar, signalSubscribers, shutdown, server)
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/curusarn/resh/internal/cfg"
+	"github.com/curusarn/resh/internal/histio"
+	"github.com/curusarn/resh/internal/msg"
+	"github.com/curusarn/resh/internal/searchapp"
+	"go.uber.org/zap"
+)
+
+type searchHandler struct {
+	sugar *zap.SugaredLogger
+	hio   *histio.Histio
+
+	deviceName string
+	ranking    cfg.Ranking
+	debug      bool
+}
+
+func (h *searchHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	timeStart := time.Now()
+	sugar := h.sugar.With(zap.String("endpoint", "/search"))
+	sugar.Debugw("Handling request, reading body ...")
+	jsn, err := io.ReadAll(r.Body)
+	if err != nil {
+		sugar.Errorw("Error reading body", "error", err)
+		http.Error(w, "could not read body", http.StatusBadRequest)
+		return
+	}
+
+	sugar.Debugw("Unmarshaling search message ...")
+	mess := msg.SearchMsg{}
+	err = json.Unmarshal(jsn, &mess)
+	if err != nil {
+		sugar.Errorw("Error during unmarshaling",
+			"error", err,
+			"payload", jsn,
+		)
+		http.Error(w, "could not decode search message", http.StatusBadRequest)
+		return
+	}
+
+	records := h.hio.CliRecords()
+	// search is canceled when the client goes away (e.g. user typed another character)
+	ctx := r.Context()
+	var results []searchapp.Result
+	if mess.Raw {
+		query := searchapp.NewRawQueryFromString(mess.Query, mess.Fuzzy, h.debug)
+		results, err = searchapp.SearchRaw(ctx, records, query, mess.Options, h.debug)
+	} else {
+		qctx := searchapp.QueryContext{
+			Host:            h.deviceName,
+			Pwd:             mess.PWD,
+			GitOriginRemote: mess.GitOriginRemote,
+			GitBranch:       mess.GitBranch,
+			SessionID:       mess.SessionID,
+			Env:             mess.Env,
+		}
+		query := searchapp.NewQueryFromString(sugar, mess.Query, qctx, h.ranking, mess.Fuzzy, h.debug)
+		results, err = searchapp.Search(ctx, records, query, mess.Options, h.debug)
+	}
+	if err != nil {
+		sugar.Debugw("Search got canceled", "error", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	enc := json.NewEncoder(w)
+	for _, res := range results {
+		err = enc.Encode(&res)
+		if err != nil {
+			sugar.Debugw("Error while writing results - client probably went away", "error", err)
+			return
+		}
+	}
+	sugar.Infow("Request handled",
+		"recordCount", len(records),
+		"resultCount", len(results),
+		"duration", time.Since(timeStart),
+	)
+}
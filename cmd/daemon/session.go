@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/curusarn/resh/internal/histio"
+	"github.com/curusarn/resh/internal/msg"
+	"go.uber.org/zap"
+)
+
+type sessionHandler struct {
+	sugar *zap.SugaredLogger
+	hio   *histio.Histio
+}
+
+func (h *sessionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	sugar := h.sugar.With(zap.String("endpoint", "/session"))
+	sugar.Debugw("Handling request, reading body ...")
+	jsn, err := io.ReadAll(r.Body)
+	if err != nil {
+		sugar.Errorw("Error reading body", "error", err)
+		http.Error(w, "could not read body", http.StatusBadRequest)
+		return
+	}
+
+	sugar.Debugw("Unmarshaling session message ...")
+	mess := msg.SessionMsg{}
+	err = json.Unmarshal(jsn, &mess)
+	if err != nil {
+		sugar.Errorw("Error during unmarshaling",
+			"error", err,
+			"payload", jsn,
+		)
+		http.Error(w, "could not decode session message", http.StatusBadRequest)
+		return
+	}
+	if mess.SessionID == "" {
+		http.Error(w, "missing session ID", http.StatusBadRequest)
+		return
+	}
+
+	resp := msg.SessionResponse{Records: h.hio.SessionCliRecords(mess.SessionID)}
+	jsn, err = json.Marshal(&resp)
+	if err != nil {
+		sugar.Errorw("Error when marshaling", "error", err)
+		return
+	}
+	w.Write(jsn)
+	sugar.Infow("Request handled",
+		"sessionID", mess.SessionID,
+		"recordCount", len(resp.Records),
+	)
+}
@@ -4,12 +4,14 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/curusarn/resh/internal/bus"
 	"github.com/curusarn/resh/internal/msg"
 	"go.uber.org/zap"
 )
 
 type statusHandler struct {
-	sugar *zap.SugaredLogger
+	sugar      *zap.SugaredLogger
+	queueStats func() []bus.Stats
 }
 
 func (h *statusHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -19,6 +21,7 @@ func (h *statusHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		Status:  true,
 		Version: version,
 		Commit:  commit,
+		Queues:  h.queueStats(),
 	}
 	jsn, err := json.Marshal(&resp)
 	if err != nil {
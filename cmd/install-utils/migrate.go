@@ -7,7 +7,9 @@ import (
 
 	"github.com/curusarn/resh/internal/cfg"
 	"github.com/curusarn/resh/internal/datadir"
+	"github.com/curusarn/resh/internal/device"
 	"github.com/curusarn/resh/internal/futil"
+	"github.com/curusarn/resh/internal/histio"
 	"github.com/curusarn/resh/internal/output"
 	"github.com/curusarn/resh/internal/recio"
 )
@@ -95,6 +97,15 @@ func migrateHistory(out *output.Output) error {
 	return migrateHistoryFormat(out)
 }
 
+// getHistoryPath returns path to history file of this device
+func getHistoryPath(dataDir string) (string, error) {
+	deviceID, err := device.GetID(dataDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to get device ID: %w", err)
+	}
+	return histio.GetPath(dataDir, deviceID), nil
+}
+
 // Find first existing history and use it
 // Don't bother with merging of history in multiple locations - it could get messy and it shouldn't be necessary
 func migrateHistoryLocation(out *output.Output) error {
@@ -102,7 +113,14 @@ func migrateHistoryLocation(out *output.Output) error {
 	if err != nil {
 		return fmt.Errorf("failed to get data directory: %w", err)
 	}
-	historyPath := path.Join(dataDir, datadir.HistoryFileName)
+	historyPath, err := getHistoryPath(dataDir)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(path.Dir(historyPath), 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
 
 	exists, err := futil.FileExists(historyPath)
 	if err != nil {
@@ -120,6 +138,7 @@ func migrateHistoryLocation(out *output.Output) error {
 	}
 
 	legacyHistoryPaths := []string{
+		path.Join(dataDir, datadir.HistoryFileName),
 		path.Join(homeDir, ".resh_history.json"),
 		path.Join(homeDir, ".resh/history.json"),
 	}
@@ -148,7 +167,10 @@ func migrateHistoryFormat(out *output.Output) error {
 	if err != nil {
 		return fmt.Errorf("could not get user data directory: %w", err)
 	}
-	historyPath := path.Join(dataDir, datadir.HistoryFileName)
+	historyPath, err := getHistoryPath(dataDir)
+	if err != nil {
+		return err
+	}
 
 	exists, err := futil.FileExists(historyPath)
 	if err != nil {
@@ -170,6 +192,8 @@ func migrateHistoryFormat(out *output.Output) error {
 
 	rio := recio.New(out.Logger.Sugar())
 
+	// Legacy and V1 records are converted to V2 when read (see recconv.V1ToV2)
+	// and written back in V2 format
 	recs, err := rio.ReadAndFixFile(historyPath, 3)
 	if err != nil {
 		return fmt.Errorf("could not load history file: %w", err)
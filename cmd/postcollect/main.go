@@ -60,7 +60,7 @@ func main() {
 		SessionID: *sessionID,
 		Shlvl:     *shlvl,
 
-		Rec: record.V1{
+		Rec: record.V2{
 			RecordID:  *recordID,
 			SessionID: *sessionID,
 
@@ -70,5 +70,5 @@ func main() {
 			PartsNotMerged: true,
 		},
 	}
-	collect.SendRecord(out, rec, strconv.Itoa(config.Port), "/record")
+	collect.SendRecord(out, rec, config, "/record")
 }
@@ -12,8 +12,6 @@ import (
 	"github.com/curusarn/resh/internal/recordint"
 	"github.com/spf13/pflag"
 	"go.uber.org/zap"
-
-	"strconv"
 )
 
 // info passed during build
@@ -44,5 +42,5 @@ func main() {
 		SessionID:  *sessionID,
 		SessionPID: *sessionPID,
 	}
-	collect.SendSessionInit(out, rec, strconv.Itoa(config.Port))
+	collect.SendSessionInit(out, rec, config)
 }
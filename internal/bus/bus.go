@@ -0,0 +1,125 @@
+// Package bus delivers events between daemon components through bounded queues
+package bus
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+)
+
+// Bus delivers each published event to all subscribers
+// Every subscriber has its own bounded queue - events are delivered in the order they were published
+// which keeps both parts of records from each session in order
+// Publishing only blocks on lossless subscribers - events are dropped for other subscribers that can't keep up
+type Bus[T any] struct {
+	sugar *zap.SugaredLogger
+	name  string
+
+	// publishing needs to be serialized to keep the order same for all subscribers
+	publishMutex sync.Mutex
+	// separate from publishing so that stats don't wait for blocked publishing
+	mutex       sync.Mutex
+	subscribers []*subscriber[T]
+}
+
+type subscriber[T any] struct {
+	name      string
+	queue     chan T
+	lossless  bool
+	delivered uint64
+	dropped   uint64
+}
+
+// Stats of one subscriber queue
+type Stats struct {
+	Bus        string `json:"bus"`
+	Subscriber string `json:"subscriber"`
+	Queued     int    `json:"queued"`
+	Capacity   int    `json:"capacity"`
+	Delivered  uint64 `json:"delivered"`
+	Dropped    uint64 `json:"dropped"`
+}
+
+// New creates bus without subscribers
+func New[T any](sugar *zap.SugaredLogger, name string) *Bus[T] {
+	return &Bus[T]{
+		sugar: sugar.With("module", "bus", "bus", name),
+		name:  name,
+	}
+}
+
+// Subscribe returns queue with given capacity that will receive all events published from now on
+// Events are dropped when the queue is full
+func (b *Bus[T]) Subscribe(name string, capacity int) <-chan T {
+	return b.subscribe(name, capacity, false)
+}
+
+// SubscribeLossless returns queue with given capacity that will receive all events published from now on
+// Publishing blocks when the queue is full - the subscriber must never wait for publishers of this bus
+func (b *Bus[T]) SubscribeLossless(name string, capacity int) <-chan T {
+	return b.subscribe(name, capacity, true)
+}
+
+func (b *Bus[T]) subscribe(name string, capacity int, lossless bool) <-chan T {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	sub := &subscriber[T]{
+		name:     name,
+		queue:    make(chan T, capacity),
+		lossless: lossless,
+	}
+	b.subscribers = append(b.subscribers, sub)
+	return sub.queue
+}
+
+func (b *Bus[T]) getSubscribers() []*subscriber[T] {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.subscribers[:len(b.subscribers):len(b.subscribers)]
+}
+
+// Publish queues the event for all subscribers
+// It waits for lossless subscribers with full queue - other subscribers never block it
+func (b *Bus[T]) Publish(event T) {
+	b.publishMutex.Lock()
+	defer b.publishMutex.Unlock()
+	for _, sub := range b.getSubscribers() {
+		if sub.lossless {
+			sub.queue <- event
+			atomic.AddUint64(&sub.delivered, 1)
+			continue
+		}
+		select {
+		case sub.queue <- event:
+			atomic.AddUint64(&sub.delivered, 1)
+		default:
+			dropped := atomic.AddUint64(&sub.dropped, 1)
+			// don't flood the log when the subscriber is stuck
+			if dropped&(dropped-1) == 0 {
+				b.sugar.Warnw("Queue is full - dropping events",
+					"subscriber", sub.name,
+					"droppedCount", dropped,
+				)
+			}
+		}
+	}
+}
+
+// Stats returns stats of all subscriber queues
+func (b *Bus[T]) Stats() []Stats {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	var stats []Stats
+	for _, sub := range b.subscribers {
+		stats = append(stats, Stats{
+			Bus:        b.name,
+			Subscriber: sub.name,
+			Queued:     len(sub.queue),
+			Capacity:   cap(sub.queue),
+			Delivered:  atomic.LoadUint64(&sub.delivered),
+			Dropped:    atomic.LoadUint64(&sub.dropped),
+		})
+	}
+	return stats
+}
@@ -0,0 +1,97 @@
+package bus
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestPublishKeepsOrder(t *testing.T) {
+	b := New[int](zap.NewNop().Sugar(), "test")
+	first := b.Subscribe("first", 100)
+	second := b.Subscribe("second", 100)
+	for i := 0; i < 100; i++ {
+		b.Publish(i)
+	}
+	for _, queue := range []<-chan int{first, second} {
+		for i := 0; i < 100; i++ {
+			if event := <-queue; event != i {
+				t.Fatalf("Expected event %d, got %d", i, event)
+			}
+		}
+	}
+}
+
+func TestPublishDropsWhenQueueIsFull(t *testing.T) {
+	b := New[string](zap.NewNop().Sugar(), "test")
+	stuck := b.Subscribe("stuck", 2)
+	fast := b.Subscribe("fast", 10)
+
+	done := make(chan bool)
+	go func() {
+		for _, event := range []string{"a", "b", "c", "d", "e"} {
+			b.Publish(event)
+		}
+		done <- true
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Publish should never block")
+	}
+
+	if len(fast) != 5 {
+		t.Fatalf("Subscriber with free queue should get all events, got %d", len(fast))
+	}
+	if first, second := <-stuck, <-stuck; first != "a" || second != "b" {
+		t.Fatalf("Full queue should keep the oldest events, got '%s', '%s'", first, second)
+	}
+	stats := b.Stats()
+	if len(stats) != 2 {
+		t.Fatalf("Expected stats of 2 subscribers, got %+v", stats)
+	}
+	if s := stats[0]; s.Subscriber != "stuck" || s.Delivered != 2 || s.Dropped != 3 || s.Capacity != 2 || s.Queued != 0 {
+		t.Fatalf("Unexpected stats of stuck subscriber: %+v", s)
+	}
+	if s := stats[1]; s.Delivered != 5 || s.Dropped != 0 || s.Queued != 5 {
+		t.Fatalf("Unexpected stats of fast subscriber: %+v", s)
+	}
+}
+
+func TestPublishWaitsForLosslessSubscriber(t *testing.T) {
+	b := New[string](zap.NewNop().Sugar(), "test")
+	lossless := b.SubscribeLossless("lossless", 2)
+	stuck := b.Subscribe("stuck", 2)
+
+	done := make(chan bool)
+	go func() {
+		for _, event := range []string{"a", "b", "c", "d", "e"} {
+			b.Publish(event)
+		}
+		done <- true
+	}()
+	select {
+	case <-done:
+		t.Fatal("Publish should wait for lossless subscriber")
+	case <-time.After(100 * time.Millisecond):
+	}
+	// stats don't wait for publishing
+	if stats := b.Stats(); stats[0].Delivered != 2 {
+		t.Fatalf("Unexpected stats of lossless subscriber: %+v", stats[0])
+	}
+
+	for _, expected := range []string{"a", "b", "c", "d", "e"} {
+		if event := <-lossless; event != expected {
+			t.Fatalf("Expected event '%s', got '%s'", expected, event)
+		}
+	}
+	<-done
+	stats := b.Stats()
+	if s := stats[0]; s.Delivered != 5 || s.Dropped != 0 {
+		t.Fatalf("Unexpected stats of lossless subscriber: %+v", s)
+	}
+	if s := stats[1]; s.Delivered != 2 || s.Dropped != 3 || len(stuck) != 2 {
+		t.Fatalf("Unexpected stats of stuck subscriber: %+v", s)
+	}
+}
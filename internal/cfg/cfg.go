@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"strings"
 
 	"github.com/BurntSushi/toml"
+	"github.com/curusarn/resh/internal/envinfo"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -25,17 +27,209 @@ type configFile struct {
 	// added in v1
 	LogLevel *string
 
+	// added in v1
+	UseTCP *bool
+
+	// added in v1
+	IgnoreSpace *bool
+	HistIgnore  []string
+	Redaction   *redactionFile
+
+	// added in v1
+	CaptureEnv []string
+	GitDirty   *bool
+
+	// added in v1
+	Search *searchFile
+
+	// added in v1
+	Keybindings *Keybindings
+	Theme       *themeFile
+
+	// added in v1
+	Notify  *notifyFile
+	Plugins []Plugin
+
 	// added in legacy
 	// deprecated in v1
 	BindArrowKeysBash *bool
 	BindArrowKeysZsh  *bool
 }
 
+type redactionFile struct {
+	Builtin       *bool
+	BuiltinAction *string
+	Patterns      []RedactionPattern
+}
+
+type notifyFile struct {
+	Enabled            *bool
+	MinDurationSeconds *float64
+	Command            *string
+	OnlyUnfocused      *bool
+}
+
+type searchFile struct {
+	Matching *string
+	Join     *string
+	Ranking  *rankingFile
+}
+
+type rankingFile struct {
+	Pwd                    *float64
+	GitRepo                *float64
+	GitBranch              *float64
+	ParentDir              *float64
+	Session                *float64
+	Env                    *float64
+	Favorite               *float64
+	NonZeroExitCodePenalty *float64
+	DifferentHostPenalty   *float64
+	Frequency              *float64
+	Recency                *float64
+	RecencyHalfLifeHours   *float64
+	TimeCoef               *float64
+}
+
+// Search matching modes
+const (
+	// MatchingExact matches query terms as substrings
+	MatchingExact = "exact"
+	// MatchingFuzzy matches query terms as subsequences
+	MatchingFuzzy = "fuzzy"
+)
+
+// Join modes for multiple selected commands
+const (
+	// JoinNewline puts each command on its own line
+	JoinNewline = "newline"
+	// JoinAnd joins commands with " && "
+	JoinAnd = "and"
+	// JoinSemicolon joins commands with "; "
+	JoinSemicolon = "semicolon"
+)
+
+// Search configures the search app
+type Search struct {
+	// Matching is the default matching mode - it can be switched in the search app
+	Matching string
+	// Join is the default join mode for multiple selected commands - it can be switched in the search app
+	Join string
+	// Ranking of results in contextual mode
+	Ranking Ranking
+}
+
+// Ranking holds weights of signals used to rank results in contextual mode
+// Only the best of Pwd, GitRepo and ParentDir is used
+type Ranking struct {
+	// Pwd is added when the command was executed in the current directory
+	Pwd float64
+	// GitRepo is added when the command was executed in the current git repository
+	GitRepo float64
+	// GitBranch is added when the command was executed in the current git repository and branch
+	GitBranch float64
+	// ParentDir is added when the command was executed in a sibling or in the parent of the current directory
+	ParentDir float64
+	// Session is added when the command was executed in the current terminal session
+	Session float64
+	// Env is multiplied by the share of current CaptureEnv variables that had the same value when the command was executed
+	Env float64
+	// Favorite is added for commands marked as favorite
+	Favorite float64
+	// NonZeroExitCodePenalty is subtracted for failed commands
+	NonZeroExitCodePenalty float64
+	// DifferentHostPenalty is subtracted for commands executed on other devices
+	DifferentHostPenalty float64
+	// Frequency is multiplied by log2 of how many times the command was executed
+	Frequency float64
+	// Recency is added for just executed commands - it halves every RecencyHalfLifeHours
+	Recency              float64
+	RecencyHalfLifeHours float64
+	// TimeCoef is multiplied by unix time of the command - newer commands win ties
+	TimeCoef float64
+}
+
+// DefaultRanking is used for ranking options missing in the config
+var DefaultRanking = Ranking{
+	Pwd:                    0.9,
+	GitRepo:                0.8,
+	GitBranch:              0.3,
+	ParentDir:              0.3,
+	Session:                0.2,
+	Env:                    0.3,
+	Favorite:               0.6,
+	NonZeroExitCodePenalty: 0.4,
+	DifferentHostPenalty:   0.2,
+	Frequency:              0.05,
+	Recency:                0.2,
+	RecencyHalfLifeHours:   72,
+	TimeCoef:               1e-13,
+}
+
+// Redaction actions
+const (
+	// RedactionMask replaces the secret in the command line
+	RedactionMask = "mask"
+	// RedactionDrop drops the whole record
+	RedactionDrop = "drop"
+)
+
+// Redaction configures how secrets are removed from records before they are written to history
+type Redaction struct {
+	// Builtin enables built-in patterns for common secrets (tokens, passwords, auth headers)
+	Builtin bool
+	// BuiltinAction is used when a built-in pattern matches
+	BuiltinAction string
+	// Patterns are user defined regexes
+	Patterns []RedactionPattern
+}
+
+// RedactionPattern is a user defined regex
+// Only the first capture group is masked when the regex has one, otherwise the whole match is masked
+type RedactionPattern struct {
+	Regex  string
+	Action string
+}
+
+// Notify configures notifications about long running commands
+type Notify struct {
+	// Enabled makes the daemon send notifications
+	Enabled bool
+	// MinDurationSeconds is how long the command has to run to trigger a notification
+	MinDurationSeconds float64
+	// Command is an executable that gets the record as JSON on stdin
+	// Desktop notification is shown when it's empty
+	Command string
+	// OnlyUnfocused skips commands from terminals that are focused when the command finishes
+	OnlyUnfocused bool
+}
+
+// DefaultPluginQueueSize is used for plugins without QueueSize
+const DefaultPluginQueueSize = 1000
+
+// Plugin is an external program or named pipe that gets merged records as JSON Lines
+// Exactly one of Command and Pipe has to be set
+type Plugin struct {
+	// Name is used in logs - defaults to Command or Pipe
+	Name string
+	// Command is an executable started by the daemon that gets the records on stdin
+	// It's restarted when it exits
+	Command string
+	Args    []string
+	// Pipe is a named pipe the records are written to - it's created when it doesn't exist
+	Pipe string
+	// QueueSize is how many records wait for a slow plugin - new records are dropped when the queue is full
+	QueueSize int
+}
+
 // Config returned by this package to be used in the rest of the project
 type Config struct {
-	// Port used by daemon and rest of the components to communicate
+	// Port used by daemon and rest of the components to communicate when UseTCP is enabled
 	// Make sure to restart the daemon when you change it
 	Port int
+	// UseTCP makes the components communicate over localhost:Port instead of unix socket
+	// Any local user can connect to the port so this should only be used when unix sockets are not available
+	UseTCP bool
 
 	// BindControlR causes CTRL+R to launch the search app
 	BindControlR bool
@@ -54,6 +248,31 @@ type Config struct {
 	// Ignoring standard shell history gives us more consistent experience
 	// but you can increase this to something large to see standard shell history in RESH search
 	ReshHistoryMinSize int
+
+	// IgnoreSpace causes commands starting with space not to be recorded
+	IgnoreSpace bool
+	// HistIgnore is a list of patterns (like bash HISTIGNORE) - matching commands are not recorded
+	HistIgnore []string
+	// Redaction of secrets
+	Redaction Redaction
+
+	// CaptureEnv is a list of environment variables that are recorded with each command
+	CaptureEnv []string
+	// GitDirty causes recording if git repository has uncommitted changes
+	// It runs 'git status' before each command which can be slow in large repositories
+	GitDirty bool
+
+	// Search app options
+	Search Search
+	// Keybindings of the search app
+	Keybindings Keybindings
+	// Theme of the search app
+	Theme Theme
+
+	// Notify about long running commands
+	Notify Notify
+	// Plugins get all recorded commands
+	Plugins []Plugin
 }
 
 // defaults for config
@@ -65,6 +284,23 @@ var defaults = Config{
 	Debug:                     false,
 	SessionWatchPeriodSeconds: 600,
 	ReshHistoryMinSize:        1000,
+
+	Redaction: Redaction{
+		Builtin:       true,
+		BuiltinAction: RedactionMask,
+	},
+	CaptureEnv: []string{"VIRTUAL_ENV", "AWS_PROFILE", "KUBECONFIG", "KUBE_CONTEXT"},
+	Search: Search{
+		Matching: MatchingExact,
+		Join:     JoinNewline,
+		Ranking:  DefaultRanking,
+	},
+	Keybindings: DefaultKeybindings,
+	Theme:       Themes[ThemeDark],
+	Notify: Notify{
+		MinDurationSeconds: 30,
+		OnlyUnfocused:      true,
+	},
 }
 
 const headerComment = `##
@@ -79,8 +315,11 @@ const headerComment = `##
 ## ConfigVersion helps us seamlessly upgrade to the new formats.
 # ConfigVersion = "v1"
 
-## Port used by RESH daemon and rest of the components to communicate.
-## Make sure to restart the daemon (pkill resh-daemon) when you change it.
+## RESH daemon and rest of the components communicate over unix socket only accessible by you.
+## When UseTCP is "true" they communicate over localhost:Port instead.
+## Any local user can connect to the port and read your history - only use this when unix sockets don't work for you.
+## Make sure to restart the daemon (resh-daemon-restart) when you change these.
+# UseTCP = false
 # Port = 2627
 
 ## Controls how much and how detailed logs all RESH components produce.
@@ -101,12 +340,146 @@ const headerComment = `##
 # SessionWatchPeriodSeconds = 600
 
 ## When RESH is first installed there is no RESH history so there is nothing to search.
-## As a temporary workaround, RESH daemon parses bash/zsh shell history and searches it.
-## Once RESH history is big enough RESH stops using bash/zsh history.
+## As a temporary workaround, RESH daemon parses bash/zsh/fish shell history and searches it.
+## Once RESH history is big enough RESH stops using bash/zsh/fish history.
 ## ReshHistoryMinSize controls how big RESH history needs to be before this happens.
-## You can increase this this to e.g. 10000 to get RESH to use bash/zsh history longer.
+## You can increase this this to e.g. 10000 to get RESH to use bash/zsh/fish history longer.
 # ReshHistoryMinSize = 1000
 
+## When IgnoreSpace is "true" commands starting with space are not recorded (like HISTCONTROL=ignorespace in bash).
+## Commands starting with space are recorded by default.
+# IgnoreSpace = true
+
+## Commands matching any of the HistIgnore patterns are not recorded (like HISTIGNORE in bash).
+## Patterns have to match the whole command line - "*" matches any string, "?" matches any character.
+# HistIgnore = ["ls", "cd *", "* --help"]
+
+## Secrets are removed from commands before they are written to RESH history.
+## Secrets are either masked or the whole command is dropped.
+## Use "reshctl redact" to apply the rules to already recorded history.
+# [Redaction]
+## Builtin patterns catch common secrets - e.g. "export GITHUB_TOKEN=...", "curl -H 'Authorization: Bearer ...'", "mysql -p...".
+# Builtin = true
+## Options: "mask", "drop"
+# BuiltinAction = "mask"
+## Additional patterns - when the regex has a capture group only the group is masked.
+# [[Redaction.Patterns]]
+# Regex = 'my-secret-tool --key (\S+)'
+# Action = "mask"
+
+## Values of these environment variables are recorded with each command.
+## Search app ranks commands recorded with the same values as your current environment higher.
+## You can search by the values in the search app - e.g. "env:prod", "env:AWS_PROFILE=prod".
+## Only exported variables can be recorded. Don't add variables that contain secrets.
+# CaptureEnv = ["VIRTUAL_ENV", "AWS_PROFILE", "KUBECONFIG", "KUBE_CONTEXT"]
+
+## When GitDirty is "true" RESH records if the git repository has uncommitted changes.
+## This runs "git status" before each command which can slow down your shell in large repositories.
+# GitDirty = false
+
+## Search app options.
+# [Search]
+## Default matching mode - "exact" matches query terms as substrings, "fuzzy" matches them as subsequences (e.g. "gco" matches "git checkout").
+## You can switch between the modes in the search app using CTRL+T.
+## Options: "exact", "fuzzy"
+# Matching = "exact"
+## How multiple selected commands are joined when they are pasted onto the command line.
+## You can select multiple commands using CTRL+SPACE and switch between the modes using CTRL+Y in the search app.
+## Options: "newline", "and" (cmd1 && cmd2), "semicolon" (cmd1; cmd2)
+# Join = "newline"
+
+## Ranking of results in contextual mode - weights of signals that are added to the score of each command.
+## Matching query terms add about 1.5 (2 for whole words) per term.
+# [Search.Ranking]
+## Command was executed in the current directory, git repository, git branch (on top of the repository),
+## or in a sibling/parent directory - only the best of Pwd, GitRepo and ParentDir counts.
+# Pwd = 0.9
+# GitRepo = 0.8
+# GitBranch = 0.3
+# ParentDir = 0.3
+## Command was executed in the current terminal session.
+# Session = 0.2
+## Command was executed with the same values of CaptureEnv environment variables (e.g. the same AWS_PROFILE).
+## Only part of Env is added when only some of the variables match.
+# Env = 0.3
+## Command is marked as favorite.
+# Favorite = 0.6
+## Penalties for failed commands and commands executed on other devices.
+# NonZeroExitCodePenalty = 0.4
+# DifferentHostPenalty = 0.2
+## Frequency is multiplied by log2 of how many times the command was executed (e.g. 0.05 * 10 for 1024 executions).
+# Frequency = 0.05
+## Recency is added for just executed commands and it halves every RecencyHalfLifeHours.
+# Recency = 0.2
+# RecencyHalfLifeHours = 72
+## Multiplied by unix time of the command - newer commands win ties.
+# TimeCoef = 1e-13
+
+## Keybindings of the search app - each action is bound to a list of keys.
+## Keys set here are removed from default bindings of other actions. Use an empty list to unbind an action.
+## Keys: "ctrl+a" to "ctrl+z" (except "ctrl+h" which is backspace), "ctrl+space", "up", "down", "left", "right", "enter", "tab", "shift+tab", "esc",
+##       "home", "end", "pgup", "pgdn", "insert", "delete", "f1" to "f12"
+## Run "reshctl doctor" to check your keybindings.
+# [Keybindings]
+# Next = ["down", "ctrl+n", "tab"]
+# Prev = ["up", "ctrl+p"]
+# Paste = ["right"]
+# Execute = ["enter"]
+# Abort = ["ctrl+g"]
+# Quit = ["ctrl+c", "ctrl+d"]
+# SwitchMode = ["ctrl+r"]
+# SwitchMatching = ["ctrl+t"]
+# TimeFilter = ["ctrl+e"]
+# Favorite = ["ctrl+f"]
+# Delete = ["ctrl+x"]
+# Preview = ["ctrl+o"]
+# Session = ["ctrl+s"]
+# Select = ["ctrl+space"]
+# SwitchJoin = ["ctrl+y"]
+# SaveScript = ["f2"]
+
+## Colors of the search app.
+## Colors are disabled when NO_COLOR environment variable is set or when the terminal is monochrome.
+# [Theme]
+## Use "light" for terminals with light background.
+## Options: "dark", "light", "high-contrast", "no-color"
+# Name = "dark"
+## You can override each style of the theme - style is a color followed by any of: "bold", "underline", "reverse".
+## Colors: "black", "red", "green", "yellow", "blue", "magenta", "cyan", "white",
+##         256 color palette index (e.g. "208"), truecolor (e.g. "#ff8700")
+## Empty style uses the default color of your terminal.
+## Styles: Selected, Status, Header, Date, Host, Pwd, Match, Warn, Git, Favorite
+# Match = "#ff8700 bold"
+# Date = "244"
+
+## Daemon can notify you when a long running command finishes.
+## Make sure to restart the daemon (resh-daemon-restart) when you change these.
+# [Notify]
+# Enabled = false
+## Commands that run at least this long trigger a notification.
+# MinDurationSeconds = 30
+## Executable that gets the finished command as JSON on stdin (same format as "reshctl export").
+## Desktop notification (notify-send or osascript) is shown when Command is empty.
+# Command = "~/bin/resh-notify"
+## When OnlyUnfocused is "true" commands from focused terminals don't trigger notifications.
+## Focus is detected in tmux and X11 terminals that set $WINDOWID (requires xdotool).
+## Terminals where focus can't be detected are treated as unfocused.
+# OnlyUnfocused = true
+
+## Plugins get every recorded command as a line of JSON (same format as "reshctl export").
+## Each plugin is either an executable started by the daemon that reads the commands from stdin,
+## or a named pipe (created when it doesn't exist) that you read from.
+## Commands are dropped when a plugin can't keep up and more than QueueSize commands are waiting for it.
+## Make sure to restart the daemon (resh-daemon-restart) when you change these.
+# [[Plugins]]
+# Name = "audit"
+# Command = "~/bin/resh-audit"
+# Args = ["--verbose"]
+# QueueSize = 1000
+# [[Plugins]]
+# Name = "metrics"
+# Pipe = "/tmp/resh-metrics.fifo"
+
 `
 
 func getConfigPath() (string, error) {
@@ -165,10 +538,247 @@ func processAndFillDefaults(configF *configFile) (Config, error) {
 	if configF.BindControlR != nil {
 		config.BindControlR = *configF.BindControlR
 	}
+	if configF.UseTCP != nil {
+		config.UseTCP = *configF.UseTCP
+	}
+
+	if configF.IgnoreSpace != nil {
+		config.IgnoreSpace = *configF.IgnoreSpace
+	}
+	if configF.HistIgnore != nil {
+		config.HistIgnore = configF.HistIgnore
+	}
+	if configF.CaptureEnv != nil {
+		var errCaptureEnv error
+		config.CaptureEnv, errCaptureEnv = processCaptureEnv(configF.CaptureEnv)
+		if errCaptureEnv != nil {
+			err = errCaptureEnv
+		}
+	}
+	if configF.GitDirty != nil {
+		config.GitDirty = *configF.GitDirty
+	}
+	if configF.Redaction != nil {
+		var errRedaction error
+		config.Redaction, errRedaction = processRedaction(configF.Redaction)
+		if errRedaction != nil {
+			err = errRedaction
+		}
+	}
+
+	if configF.Search != nil {
+		var errSearch error
+		config.Search, errSearch = processSearch(configF.Search)
+		if errSearch != nil {
+			err = errSearch
+		}
+	}
+
+	if configF.Keybindings != nil {
+		var errKeybindings error
+		config.Keybindings, errKeybindings = processKeybindings(configF.Keybindings)
+		if errKeybindings != nil {
+			err = errKeybindings
+		}
+	}
+	if configF.Theme != nil {
+		var errTheme error
+		config.Theme, errTheme = processTheme(configF.Theme)
+		if errTheme != nil {
+			err = errTheme
+		}
+	}
+	if configF.Plugins != nil {
+		var errPlugins error
+		config.Plugins, errPlugins = processPlugins(configF.Plugins)
+		if errPlugins != nil {
+			err = errPlugins
+		}
+	}
+	if configF.Notify != nil {
+		var errNotify error
+		config.Notify, errNotify = processNotify(configF.Notify)
+		if errNotify != nil {
+			err = errNotify
+		}
+	}
 
 	return config, err
 }
 
+func processSearch(searchF *searchFile) (Search, error) {
+	search := defaults.Search
+	var err error
+	if searchF.Matching != nil {
+		if *searchF.Matching == MatchingExact || *searchF.Matching == MatchingFuzzy {
+			search.Matching = *searchF.Matching
+		} else {
+			err = fmt.Errorf("unknown search matching mode '%s'", *searchF.Matching)
+		}
+	}
+	if searchF.Join != nil {
+		if *searchF.Join == JoinNewline || *searchF.Join == JoinAnd || *searchF.Join == JoinSemicolon {
+			search.Join = *searchF.Join
+		} else {
+			err = fmt.Errorf("unknown join mode '%s'", *searchF.Join)
+		}
+	}
+	if searchF.Ranking != nil {
+		var errRanking error
+		search.Ranking, errRanking = processRanking(searchF.Ranking)
+		if errRanking != nil {
+			err = errRanking
+		}
+	}
+	return search, err
+}
+
+func processRanking(rankingF *rankingFile) (Ranking, error) {
+	ranking := defaults.Search.Ranking
+	var err error
+	set := func(value *float64, dst *float64) {
+		if value != nil {
+			*dst = *value
+		}
+	}
+	set(rankingF.Pwd, &ranking.Pwd)
+	set(rankingF.GitRepo, &ranking.GitRepo)
+	set(rankingF.GitBranch, &ranking.GitBranch)
+	set(rankingF.ParentDir, &ranking.ParentDir)
+	set(rankingF.Session, &ranking.Session)
+	set(rankingF.Env, &ranking.Env)
+	set(rankingF.Favorite, &ranking.Favorite)
+	set(rankingF.NonZeroExitCodePenalty, &ranking.NonZeroExitCodePenalty)
+	set(rankingF.DifferentHostPenalty, &ranking.DifferentHostPenalty)
+	set(rankingF.Frequency, &ranking.Frequency)
+	set(rankingF.Recency, &ranking.Recency)
+	set(rankingF.TimeCoef, &ranking.TimeCoef)
+	if rankingF.RecencyHalfLifeHours != nil {
+		if *rankingF.RecencyHalfLifeHours > 0 {
+			ranking.RecencyHalfLifeHours = *rankingF.RecencyHalfLifeHours
+		} else {
+			err = fmt.Errorf("RecencyHalfLifeHours needs to be positive, got %v", *rankingF.RecencyHalfLifeHours)
+		}
+	}
+	return ranking, err
+}
+
+// invalid names are skipped
+func processCaptureEnv(names []string) ([]string, error) {
+	var err error
+	valid := []string{}
+	for _, name := range names {
+		if !envinfo.IsValidName(name) {
+			err = fmt.Errorf("invalid environment variable name '%s' in CaptureEnv", name)
+			continue
+		}
+		valid = append(valid, name)
+	}
+	return valid, err
+}
+
+func isValidRedactionAction(action string) bool {
+	return action == RedactionMask || action == RedactionDrop
+}
+
+// invalid patterns are skipped
+func processRedaction(redactionF *redactionFile) (Redaction, error) {
+	redaction := defaults.Redaction
+	var err error
+	if redactionF.Builtin != nil {
+		redaction.Builtin = *redactionF.Builtin
+	}
+	if redactionF.BuiltinAction != nil {
+		if isValidRedactionAction(*redactionF.BuiltinAction) {
+			redaction.BuiltinAction = *redactionF.BuiltinAction
+		} else {
+			err = fmt.Errorf("unknown redaction action '%s'", *redactionF.BuiltinAction)
+		}
+	}
+	for _, pattern := range redactionF.Patterns {
+		if pattern.Action == "" {
+			pattern.Action = RedactionMask
+		}
+		if !isValidRedactionAction(pattern.Action) {
+			err = fmt.Errorf("unknown redaction action '%s' for pattern '%s'", pattern.Action, pattern.Regex)
+			continue
+		}
+		redaction.Patterns = append(redaction.Patterns, pattern)
+	}
+	return redaction, err
+}
+
+func processNotify(notifyF *notifyFile) (Notify, error) {
+	notify := defaults.Notify
+	var err error
+	if notifyF.Enabled != nil {
+		notify.Enabled = *notifyF.Enabled
+	}
+	if notifyF.OnlyUnfocused != nil {
+		notify.OnlyUnfocused = *notifyF.OnlyUnfocused
+	}
+	if notifyF.MinDurationSeconds != nil {
+		if *notifyF.MinDurationSeconds >= 0 {
+			notify.MinDurationSeconds = *notifyF.MinDurationSeconds
+		} else {
+			err = fmt.Errorf("MinDurationSeconds can't be negative, got %v", *notifyF.MinDurationSeconds)
+		}
+	}
+	if notifyF.Command != nil {
+		var errExpand error
+		notify.Command, errExpand = expandHome(*notifyF.Command)
+		if errExpand != nil {
+			err = fmt.Errorf("could not expand notify command: %w", errExpand)
+		}
+	}
+	return notify, err
+}
+
+// invalid plugins are skipped
+func processPlugins(pluginsF []Plugin) ([]Plugin, error) {
+	var err error
+	plugins := []Plugin{}
+	for _, plugin := range pluginsF {
+		if (plugin.Command == "") == (plugin.Pipe == "") {
+			err = fmt.Errorf("plugin '%s' needs either Command or Pipe", plugin.Name)
+			continue
+		}
+		var errExpand error
+		if plugin.Command != "" {
+			plugin.Command, errExpand = expandHome(plugin.Command)
+		} else {
+			plugin.Pipe, errExpand = expandHome(plugin.Pipe)
+		}
+		if errExpand != nil {
+			err = fmt.Errorf("could not expand path of plugin '%s': %w", plugin.Name, errExpand)
+			continue
+		}
+		if plugin.Name == "" {
+			plugin.Name = plugin.Command + plugin.Pipe
+		}
+		if plugin.QueueSize == 0 {
+			plugin.QueueSize = DefaultPluginQueueSize
+		} else if plugin.QueueSize < 0 {
+			err = fmt.Errorf("QueueSize of plugin '%s' can't be negative, got %d", plugin.Name, plugin.QueueSize)
+			continue
+		}
+		plugins = append(plugins, plugin)
+	}
+	return plugins, err
+}
+
+// expandHome expands leading "~/" to user home directory
+func expandHome(p string) (string, error) {
+	if !strings.HasPrefix(p, "~/") {
+		return p, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return p, fmt.Errorf("could not get user home dir: %w", err)
+	}
+	return path.Join(home, p[2:]), nil
+}
+
 // New returns a config file
 // returned config is always usable, returned errors are informative
 func New() (Config, error) {
@@ -0,0 +1,204 @@
+package cfg
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Keybindings maps search app actions to keys
+// Keys are lowercase names - e.g. "ctrl+r", "up", "enter", "f1" - see KeyNames()
+type Keybindings struct {
+	// Next and Prev move the selection
+	Next []string
+	Prev []string
+	// Paste selected command onto the command line
+	Paste []string
+	// Execute selected command
+	Execute []string
+	// Abort returns the original command line
+	Abort []string
+	// Quit the search app
+	Quit []string
+	// SwitchMode switches between contextual and raw mode
+	SwitchMode []string
+	// SwitchMatching switches between exact and fuzzy matching
+	SwitchMatching []string
+	// TimeFilter cycles through time ranges - today, this week, this month, all
+	TimeFilter []string
+	// Favorite toggles favorite flag of selected command
+	Favorite []string
+	// Delete selected command from history
+	Delete []string
+	// Preview toggles the preview pane
+	Preview []string
+	// Session switches to session replay of selected command
+	Session []string
+	// Select marks selected command to be pasted together with other marked commands
+	Select []string
+	// SwitchJoin switches how multiple selected commands are joined
+	SwitchJoin []string
+	// SaveScript saves selected commands as a script
+	// Bound to a function key by default - ctrl+w deletes a word in shells and it's easy to press by accident
+	SaveScript []string
+}
+
+// DefaultKeybindings are used for actions missing in the config
+var DefaultKeybindings = Keybindings{
+	Next:           []string{"down", "ctrl+n", "tab"},
+	Prev:           []string{"up", "ctrl+p"},
+	Paste:          []string{"right"},
+	Execute:        []string{"enter"},
+	Abort:          []string{"ctrl+g"},
+	Quit:           []string{"ctrl+c", "ctrl+d"},
+	SwitchMode:     []string{"ctrl+r"},
+	SwitchMatching: []string{"ctrl+t"},
+	TimeFilter:     []string{"ctrl+e"},
+	Favorite:       []string{"ctrl+f"},
+	Delete:         []string{"ctrl+x"},
+	Preview:        []string{"ctrl+o"},
+	Session:        []string{"ctrl+s"},
+	Select:         []string{"ctrl+space"},
+	SwitchJoin:     []string{"ctrl+y"},
+	SaveScript:     []string{"f2"},
+}
+
+// keybindingAction is a named action with pointer to its keys
+type keybindingAction struct {
+	Name string
+	Keys *[]string
+}
+
+// actions returns all actions in stable order
+func (kb *Keybindings) actions() []keybindingAction {
+	return []keybindingAction{
+		{"Next", &kb.Next},
+		{"Prev", &kb.Prev},
+		{"Paste", &kb.Paste},
+		{"Execute", &kb.Execute},
+		{"Abort", &kb.Abort},
+		{"Quit", &kb.Quit},
+		{"SwitchMode", &kb.SwitchMode},
+		{"SwitchMatching", &kb.SwitchMatching},
+		{"TimeFilter", &kb.TimeFilter},
+		{"Favorite", &kb.Favorite},
+		{"Delete", &kb.Delete},
+		{"Preview", &kb.Preview},
+		{"Session", &kb.Session},
+		{"Select", &kb.Select},
+		{"SwitchJoin", &kb.SwitchJoin},
+		{"SaveScript", &kb.SaveScript},
+	}
+}
+
+var specialKeys = []string{
+	"up", "down", "left", "right",
+	"enter", "tab", "shift+tab", "esc",
+	"home", "end", "pgup", "pgdn", "insert", "delete",
+	"ctrl+space",
+}
+
+// KeyNames returns names of all keys that can be used in keybindings
+func KeyNames() []string {
+	names := append([]string{}, specialKeys...)
+	for c := 'a'; c <= 'z'; c++ {
+		name := "ctrl+" + string(c)
+		if _, found := reservedKeys[name]; found {
+			continue
+		}
+		names = append(names, name)
+	}
+	for i := 1; i <= 12; i++ {
+		names = append(names, fmt.Sprintf("f%d", i))
+	}
+	return names
+}
+
+var keyAliases = map[string]string{
+	"arrowup":    "up",
+	"arrowdown":  "down",
+	"arrowleft":  "left",
+	"arrowright": "right",
+	"return":     "enter",
+	"escape":     "esc",
+	"backtab":    "shift+tab",
+	"pageup":     "pgup",
+	"pagedown":   "pgdn",
+	"del":        "delete",
+	// terminals send the same codes for these
+	"ctrl+i": "tab",
+	"ctrl+m": "enter",
+}
+
+// reservedKeys can't be bound because terminals send the same codes for keys used to edit the query
+var reservedKeys = map[string]string{
+	"ctrl+h": "backspace",
+}
+
+// normalizeKey returns canonical name of the key
+func normalizeKey(key string) (string, error) {
+	name := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(key), "-", "+"))
+	if alias, found := keyAliases[name]; found {
+		name = alias
+	}
+	if reserved, found := reservedKeys[name]; found {
+		return "", fmt.Errorf("key '%s' can't be bound because it is the same as %s", key, reserved)
+	}
+	for _, valid := range KeyNames() {
+		if name == valid {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("unknown key '%s'", key)
+}
+
+// keys set in the config take precedence - they are removed from default bindings of other actions
+// invalid and conflicting keys are skipped
+func processKeybindings(kbF *Keybindings) (Keybindings, error) {
+	kb := defaults.Keybindings
+	actions := kb.actions()
+	actionsF := kbF.actions()
+	var problems []string
+	bound := map[string]string{}
+	// actions without any valid keys keep their default keys
+	set := make([]bool, len(actions))
+	for i, actionF := range actionsF {
+		if *actionF.Keys == nil {
+			continue
+		}
+		keys := []string{}
+		for _, key := range *actionF.Keys {
+			name, err := normalizeKey(key)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %v", actionF.Name, err))
+				continue
+			}
+			if other, found := bound[name]; found {
+				problems = append(problems, fmt.Sprintf("key '%s' is bound to both %s and %s", name, other, actionF.Name))
+				continue
+			}
+			bound[name] = actionF.Name
+			keys = append(keys, name)
+		}
+		if len(keys) == 0 && len(*actionF.Keys) > 0 {
+			continue
+		}
+		*actions[i].Keys = keys
+		set[i] = true
+	}
+	for i, action := range actions {
+		if set[i] {
+			continue
+		}
+		keys := []string{}
+		for _, name := range *action.Keys {
+			if _, found := bound[name]; !found {
+				keys = append(keys, name)
+			}
+		}
+		*action.Keys = keys
+	}
+	if len(problems) > 0 {
+		return kb, fmt.Errorf("invalid keybindings: %s", strings.Join(problems, "; "))
+	}
+	return kb, nil
+}
@@ -0,0 +1,64 @@
+package cfg
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestProcessKeybindings(t *testing.T) {
+	kb, err := processKeybindings(&Keybindings{
+		Next:   []string{"Ctrl-J", "down"},
+		Prev:   []string{"ctrl+k", "up"},
+		Abort:  []string{"esc"},
+		Delete: []string{},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := map[string][]string{
+		"Next":   {"ctrl+j", "down"},
+		"Prev":   {"ctrl+k", "up"},
+		"Abort":  {"esc"},
+		"Delete": {},
+		// tab is kept because Next was overridden
+		"Paste": {"right"},
+		"Quit":  {"ctrl+c", "ctrl+d"},
+	}
+	for _, action := range kb.actions() {
+		if keys, found := expected[action.Name]; found && !reflect.DeepEqual(*action.Keys, keys) {
+			t.Errorf("Unexpected keys for %s: %v", action.Name, *action.Keys)
+		}
+	}
+
+	// user defined keys are removed from defaults of other actions
+	kb, err = processKeybindings(&Keybindings{Paste: []string{"right", "ctrl+r"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(kb.SwitchMode) != 0 {
+		t.Errorf("Expected ctrl+r to be removed from SwitchMode, got %v", kb.SwitchMode)
+	}
+	if !reflect.DeepEqual(DefaultKeybindings.SwitchMode, []string{"ctrl+r"}) {
+		t.Errorf("Defaults were modified: %v", DefaultKeybindings.SwitchMode)
+	}
+}
+
+func TestProcessKeybindingsErrors(t *testing.T) {
+	data := []Keybindings{
+		{Next: []string{"j"}},
+		{Next: []string{"ctrl+shift+x"}},
+		{Next: []string{"ctrl+j"}, Prev: []string{"ctrl+j"}},
+		{Next: []string{"tab"}, Prev: []string{"ctrl+i"}},
+		{Next: []string{"ctrl+h"}},
+	}
+	for _, kbF := range data {
+		kb, err := processKeybindings(&kbF)
+		if err == nil {
+			t.Errorf("Expected error for %+v", kbF)
+		}
+		// config is still usable
+		if len(kb.Execute) == 0 || len(kb.Next) == 0 || len(kb.Prev) == 0 {
+			t.Errorf("Expected usable keybindings for %+v, got %+v", kbF, kb)
+		}
+	}
+}
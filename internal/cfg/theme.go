@@ -0,0 +1,188 @@
+package cfg
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type themeFile struct {
+	Name     *string
+	Selected *string
+	Status   *string
+	Header   *string
+	Date     *string
+	Host     *string
+	Pwd      *string
+	Match    *string
+	Warn     *string
+	Git      *string
+	Favorite *string
+}
+
+// Theme holds styles of the search app
+// Each style is a list of SGR parameters (e.g. "35;1") - empty style keeps the default terminal color
+type Theme struct {
+	// Selected is used for the highlighted item
+	Selected string
+	// Status is used for the status line
+	Status string
+	// Header is used for column headers
+	Header string
+	// Date is used for times of commands
+	Date string
+	// Host is used for device names
+	Host string
+	// Pwd is used for directories
+	Pwd string
+	// Match is used for matched parts of commands
+	Match string
+	// Warn is used for non-zero exit codes
+	Warn string
+	// Git is used for the git repository flag
+	Git string
+	// Favorite is used for the favorite flag
+	Favorite string
+}
+
+// Builtin themes
+const (
+	ThemeDark         = "dark"
+	ThemeLight        = "light"
+	ThemeHighContrast = "high-contrast"
+	ThemeNoColor      = "no-color"
+)
+
+// Themes are builtin themes
+var Themes = map[string]Theme{
+	ThemeDark: {
+		Selected: "7;1",
+		Status:   "7;1",
+		Header:   "4",
+		Date:     "33",
+		Host:     "31",
+		Pwd:      "34;1",
+		Match:    "35;1",
+		Warn:     "31;1",
+		Git:      "32;1",
+		Favorite: "33;1",
+	},
+	ThemeLight: {
+		Selected: "7;1",
+		Status:   "7;1",
+		Header:   "4",
+		Date:     "38;5;94",
+		Host:     "38;5;124",
+		Pwd:      "38;5;19;1",
+		Match:    "38;5;127;1",
+		Warn:     "38;5;160;1",
+		Git:      "38;5;28;1",
+		Favorite: "38;5;130;1",
+	},
+	ThemeHighContrast: {
+		Selected: "7;1",
+		Status:   "7;1",
+		Header:   "4;1",
+		Date:     "",
+		Host:     "1",
+		Pwd:      "1",
+		Match:    "4;1",
+		Warn:     "31;1",
+		Git:      "1",
+		Favorite: "1",
+	},
+	ThemeNoColor: {
+		Selected: "7",
+		Status:   "7",
+		Header:   "4",
+		Match:    "1",
+		Warn:     "1",
+		Git:      "1",
+		Favorite: "1",
+	},
+}
+
+var basicColors = map[string]int{
+	"black":   0,
+	"red":     1,
+	"green":   2,
+	"yellow":  3,
+	"blue":    4,
+	"magenta": 5,
+	"cyan":    6,
+	"white":   7,
+}
+
+var effects = map[string]string{
+	"bold":      "1",
+	"underline": "4",
+	"reverse":   "7",
+}
+
+// parseStyle turns style like "bold #ff8700" into SGR parameters
+// Color goes first because that's what the search app TUI expects
+func parseStyle(style string) (string, error) {
+	var color string
+	var params []string
+	for _, word := range strings.Fields(strings.ToLower(style)) {
+		if param, found := effects[word]; found {
+			params = append(params, param)
+			continue
+		}
+		if color != "" {
+			return "", fmt.Errorf("unknown effect or multiple colors in style '%s'", style)
+		}
+		if c, found := basicColors[word]; found {
+			color = strconv.Itoa(30 + c)
+		} else if c, err := strconv.Atoi(word); err == nil && c >= 0 && c < 256 {
+			color = "38;5;" + word
+		} else if len(word) == 7 && word[0] == '#' {
+			rgb, err := strconv.ParseUint(word[1:], 16, 32)
+			if err != nil {
+				return "", fmt.Errorf("invalid color '%s' in style '%s'", word, style)
+			}
+			color = fmt.Sprintf("38;2;%d;%d;%d", rgb>>16, (rgb>>8)&0xff, rgb&0xff)
+		} else {
+			return "", fmt.Errorf("unknown color or effect '%s' in style '%s'", word, style)
+		}
+	}
+	if color != "" {
+		params = append([]string{color}, params...)
+	}
+	return strings.Join(params, ";"), nil
+}
+
+// invalid styles are skipped
+func processTheme(themeF *themeFile) (Theme, error) {
+	theme := defaults.Theme
+	var err error
+	if themeF.Name != nil {
+		if t, found := Themes[*themeF.Name]; found {
+			theme = t
+		} else {
+			err = fmt.Errorf("unknown theme '%s'", *themeF.Name)
+		}
+	}
+	set := func(style *string, dst *string) {
+		if style == nil {
+			return
+		}
+		sgr, errStyle := parseStyle(*style)
+		if errStyle != nil {
+			err = errStyle
+			return
+		}
+		*dst = sgr
+	}
+	set(themeF.Selected, &theme.Selected)
+	set(themeF.Status, &theme.Status)
+	set(themeF.Header, &theme.Header)
+	set(themeF.Date, &theme.Date)
+	set(themeF.Host, &theme.Host)
+	set(themeF.Pwd, &theme.Pwd)
+	set(themeF.Match, &theme.Match)
+	set(themeF.Warn, &theme.Warn)
+	set(themeF.Git, &theme.Git)
+	set(themeF.Favorite, &theme.Favorite)
+	return theme, err
+}
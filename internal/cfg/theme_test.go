@@ -0,0 +1,47 @@
+package cfg
+
+import "testing"
+
+func TestParseStyle(t *testing.T) {
+	data := map[string]string{
+		"":                 "",
+		"bold":             "1",
+		"magenta bold":     "35;1",
+		"bold magenta":     "35;1",
+		"208 underline":    "38;5;208;4",
+		"#ff8700 Bold":     "38;2;255;135;0;1",
+		"reverse":          "7",
+		"cyan":             "36",
+		"underline 0 bold": "38;5;0;4;1",
+	}
+	for style, expected := range data {
+		sgr, err := parseStyle(style)
+		if err != nil {
+			t.Fatalf("Unexpected error for style '%s': %v", style, err)
+		}
+		if sgr != expected {
+			t.Errorf("Unexpected SGR for style '%s': expected '%s', got '%s'", style, expected, sgr)
+		}
+	}
+	for _, style := range []string{"red blue", "256", "#ff87", "#gg8700", "blinking"} {
+		if _, err := parseStyle(style); err == nil {
+			t.Errorf("Expected error for style '%s'", style)
+		}
+	}
+}
+
+func TestProcessTheme(t *testing.T) {
+	name := ThemeLight
+	match := "#ff8700 bold"
+	date := "purple"
+	theme, err := processTheme(&themeFile{Name: &name, Match: &match, Date: &date})
+	if err == nil {
+		t.Fatalf("Expected error for invalid style")
+	}
+	if theme.Match != "38;2;255;135;0;1" {
+		t.Errorf("Unexpected match style: %s", theme.Match)
+	}
+	if theme.Date != Themes[ThemeLight].Date || theme.Pwd != Themes[ThemeLight].Pwd {
+		t.Errorf("Expected styles of light theme to be kept, got %+v", theme)
+	}
+}
@@ -1,6 +1,7 @@
 package check
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -15,7 +16,7 @@ func LoginShell() (string, error) {
 	}
 	parts := strings.Split(shellPath, "/")
 	shell := parts[len(parts)-1]
-	if shell != "bash" && shell != "zsh" {
+	if shell != "bash" && shell != "zsh" && shell != "fish" {
 		return fmt.Sprintf("Current shell (%s) is unsupported\n", shell), nil
 	}
 	return "", nil
@@ -70,6 +71,27 @@ func ZshVersion() (string, error) {
 	return "", nil
 }
 
+func FishVersion() (string, error) {
+	out, err := exec.Command("fish", "-c", "echo $version").Output()
+	if err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			// unlike bash and zsh, fish is not installed on most systems - nothing to report
+			return "", nil
+		}
+		return "", fmt.Errorf("command failed: %w", err)
+	}
+	verStr := strings.TrimSuffix(string(out), "\n")
+	ver, err := parseVersion(verStr)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse version: %w", err)
+	}
+
+	if ver.Major < 3 || (ver.Major == 3 && ver.Minor < 1) {
+		return msgShellVersion("fish", "3.1", verStr), nil
+	}
+	return "", nil
+}
+
 type version struct {
 	Major int
 	Minor int
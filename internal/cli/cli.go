@@ -0,0 +1,193 @@
+// Package cli implements communication between the daemon and the search apps
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/curusarn/resh/internal/cfg"
+	"github.com/curusarn/resh/internal/httpclient"
+	"github.com/curusarn/resh/internal/msg"
+	"github.com/curusarn/resh/internal/output"
+	"github.com/curusarn/resh/internal/recordint"
+	"github.com/curusarn/resh/internal/searchapp"
+)
+
+// SendCliMsg to daemon
+func SendCliMsg(out *output.Output, m msg.CliMsg, config cfg.Config) msg.CliResponse {
+	sugar := out.Logger.Sugar()
+	recJSON, err := json.Marshal(m)
+	if err != nil {
+		out.FatalE("Failed to marshal message", err)
+	}
+
+	req, err := http.NewRequest(
+		"POST",
+		httpclient.URL(config, "/dump"),
+		bytes.NewBuffer(recJSON))
+	if err != nil {
+		out.FatalE("Failed to build request", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client, err := httpclient.New(config, 3*time.Second)
+	if err != nil {
+		out.FatalE("Failed to create client", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		out.FatalDaemonNotRunning(err)
+	}
+
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		out.FatalE("Failed read response", err)
+	}
+	// sugar.Println(string(body))
+	response := msg.CliResponse{}
+	err = json.Unmarshal(body, &response)
+	if err != nil {
+		out.FatalE("Failed decode response", err)
+	}
+	sugar.Debugw("Received records from daemon",
+		"recordCount", len(response.Records),
+	)
+	return response
+}
+
+// SendFlagMsg to daemon
+func SendFlagMsg(flag recordint.Flag, config cfg.Config) (*msg.FlagResponse, error) {
+	recJSON, err := json.Marshal(flag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	req, err := http.NewRequest(
+		"POST",
+		httpclient.URL(config, "/flag"),
+		bytes.NewBuffer(recJSON))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client, err := httpclient.New(config, 3*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("daemon responded with '%s': %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	response := msg.FlagResponse{}
+	err = json.Unmarshal(body, &response)
+	if err != nil {
+		return nil, fmt.Errorf("failed decode response: %w", err)
+	}
+	return &response, nil
+}
+
+// GetSession asks daemon for all records of the session
+func GetSession(sessionID string, config cfg.Config) (*msg.SessionResponse, error) {
+	recJSON, err := json.Marshal(msg.SessionMsg{SessionID: sessionID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	req, err := http.NewRequest(
+		"POST",
+		httpclient.URL(config, "/session"),
+		bytes.NewBuffer(recJSON))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client, err := httpclient.New(config, 3*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("daemon responded with '%s': %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	response := msg.SessionResponse{}
+	err = json.Unmarshal(body, &response)
+	if err != nil {
+		return nil, fmt.Errorf("failed decode response: %w", err)
+	}
+	return &response, nil
+}
+
+// Search sends query to daemon and reads the streamed results
+// Search is canceled on the daemon side when ctx gets canceled
+func Search(ctx context.Context, m msg.SearchMsg, config cfg.Config) ([]searchapp.Result, error) {
+	recJSON, err := json.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx,
+		"POST",
+		httpclient.URL(config, "/search"),
+		bytes.NewBuffer(recJSON))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client, err := httpclient.New(config, 10*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("daemon responded with '%s': %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var results []searchapp.Result
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var res searchapp.Result
+		err = dec.Decode(&res)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed decode response: %w", err)
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
@@ -10,13 +10,15 @@ import (
 	"strings"
 	"time"
 
+	"github.com/curusarn/resh/internal/cfg"
+	"github.com/curusarn/resh/internal/httpclient"
 	"github.com/curusarn/resh/internal/output"
 	"github.com/curusarn/resh/internal/recordint"
 	"go.uber.org/zap"
 )
 
 // SendRecord to daemon
-func SendRecord(out *output.Output, r recordint.Collect, port, path string) {
+func SendRecord(out *output.Output, r recordint.Collect, config cfg.Config, path string) {
 	out.Logger.Debug("Sending record ...",
 		zap.String("cmdLine", r.Rec.CmdLine),
 		zap.String("sessionID", r.SessionID),
@@ -26,15 +28,16 @@ func SendRecord(out *output.Output, r recordint.Collect, port, path string) {
 		out.FatalE("Error while encoding record", err)
 	}
 
-	req, err := http.NewRequest("POST", "http://localhost:"+port+path,
+	req, err := http.NewRequest("POST", httpclient.URL(config, path),
 		bytes.NewBuffer(recJSON))
 	if err != nil {
 		out.FatalE("Error while sending record", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	client := http.Client{
-		Timeout: 1 * time.Second,
+	client, err := httpclient.New(config, 1*time.Second)
+	if err != nil {
+		out.FatalE("Error while creating client", err)
 	}
 	_, err = client.Do(req)
 	if err != nil {
@@ -43,7 +46,7 @@ func SendRecord(out *output.Output, r recordint.Collect, port, path string) {
 }
 
 // SendSessionInit to daemon
-func SendSessionInit(out *output.Output, r recordint.SessionInit, port string) {
+func SendSessionInit(out *output.Output, r recordint.SessionInit, config cfg.Config) {
 	out.Logger.Debug("Sending session init ...",
 		zap.String("sessionID", r.SessionID),
 		zap.Int("sessionPID", r.SessionPID),
@@ -53,15 +56,16 @@ func SendSessionInit(out *output.Output, r recordint.SessionInit, port string) {
 		out.FatalE("Error while encoding record", err)
 	}
 
-	req, err := http.NewRequest("POST", "http://localhost:"+port+"/session_init",
+	req, err := http.NewRequest("POST", httpclient.URL(config, "/session_init"),
 		bytes.NewBuffer(recJSON))
 	if err != nil {
 		out.FatalE("Error while sending record", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	client := http.Client{
-		Timeout: 1 * time.Second,
+	client, err := httpclient.New(config, 1*time.Second)
+	if err != nil {
+		out.FatalE("Error while creating client", err)
 	}
 	_, err = client.Do(req)
 	if err != nil {
@@ -9,6 +9,14 @@ import (
 // Maybe there is a better place for this constant
 const HistoryFileName = "history.reshjson"
 
+// HistoryDirName is the directory with history files of all devices
+// Each device writes its records to '<HistoryDirName>/<deviceID>'
+const HistoryDirName = "history"
+
+// IndexDirName is the directory with indexes of history files
+// Indexes are only a cache - they are rebuilt from history files when missing or outdated
+const IndexDirName = "index"
+
 func GetPath() (string, error) {
 	reshDir := "resh"
 	xdgDir, found := os.LookupEnv("XDG_DATA_HOME")
@@ -34,3 +42,19 @@ func MakePath() (string, error) {
 	}
 	return path, nil
 }
+
+// GetSocketPath returns path to unix socket of RESH daemon
+// Socket is placed in user runtime dir when available
+// The socket directory should only be accessible by the user
+func GetSocketPath() (string, error) {
+	const fname = "daemon.sock"
+	runtimeDir, found := os.LookupEnv("XDG_RUNTIME_DIR")
+	if found && runtimeDir != "" {
+		return path.Join(runtimeDir, "resh", fname), nil
+	}
+	dataDir, err := GetPath()
+	if err != nil {
+		return "", err
+	}
+	return path.Join(dataDir, "run", fname), nil
+}
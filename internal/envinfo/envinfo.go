@@ -0,0 +1,31 @@
+// Package envinfo gets snapshots of environment variables
+package envinfo
+
+import (
+	"os"
+	"regexp"
+)
+
+var nameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// IsValidName returns true if name can be a name of an environment variable
+func IsValidName(name string) bool {
+	return nameRegex.MatchString(name)
+}
+
+// Get values of given environment variables
+// Unset and empty variables are left out - returns nil when none of the variables are set
+func Get(names []string) map[string]string {
+	var env map[string]string
+	for _, name := range names {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		if env == nil {
+			env = make(map[string]string, len(names))
+		}
+		env[name] = value
+	}
+	return env
+}
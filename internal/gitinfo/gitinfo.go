@@ -0,0 +1,82 @@
+// Package gitinfo gets information about git repository of a directory
+package gitinfo
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// git is called from shell hooks - don't block the shell for too long
+// the timeout is shared by all git calls
+const timeout = 500 * time.Millisecond
+
+// Info about git repository
+type Info struct {
+	// empty when HEAD is detached
+	Branch   string
+	Commit   string
+	Toplevel string
+	// uncommitted changes to tracked files
+	Dirty bool
+}
+
+// Get info about git repository of dir
+// Checking for uncommitted changes can be slow in large repositories - it's only done when dirty is true
+// Returns empty info when dir is not in a git repository or git is not installed
+func Get(dir string, dirty bool) Info {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return get(ctx, dir, dirty)
+}
+
+func get(ctx context.Context, dir string, dirty bool) Info {
+	toplevel, err := git(ctx, dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return Info{}
+	}
+	info := Info{Toplevel: toplevel}
+	// fails in repositories without commits
+	out, err := git(ctx, dir, "rev-parse", "HEAD", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return info
+	}
+	commit, branch, _ := strings.Cut(out, "\n")
+	info.Commit = commit
+	if branch != "HEAD" {
+		info.Branch = branch
+	}
+	if !dirty {
+		return info
+	}
+	status, err := git(ctx, dir, "status", "--porcelain", "--untracked-files=no")
+	if err == nil && status != "" {
+		info.Dirty = true
+	}
+	return info
+}
+
+// Branch returns current branch of dir or empty string
+func Branch(dir string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	branch, err := git(ctx, dir, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil || branch == "HEAD" {
+		return ""
+	}
+	return branch
+}
+
+func git(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	// don't compete with the user for the index lock
+	cmd.Env = append(os.Environ(), "GIT_OPTIONAL_LOCKS=0")
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
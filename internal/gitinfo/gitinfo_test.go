@@ -0,0 +1,81 @@
+package gitinfo
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func gitInit(t *testing.T) string {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "--allow-empty", "-m", "init"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("Test setup failed: %v: %s", err, out)
+		}
+	}
+	return dir
+}
+
+func TestGet(t *testing.T) {
+	dir := gitInit(t)
+	// temp dir can be behind a symlink
+	toplevel, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		t.Fatalf("Test setup failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "file"), []byte("a"), 0644); err != nil {
+		t.Fatalf("Test setup failed: %v", err)
+	}
+
+	info := Get(dir, true)
+	if info.Branch != "main" || len(info.Commit) != 40 || info.Dirty {
+		t.Fatalf("Unexpected info: %+v", info)
+	}
+	if resolved, _ := filepath.EvalSymlinks(info.Toplevel); resolved != toplevel {
+		t.Fatalf("Expected toplevel '%s', got '%s'", toplevel, info.Toplevel)
+	}
+
+	cmd := exec.Command("git", "add", "file")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("Test setup failed: %v: %s", err, out)
+	}
+	if !Get(dir, true).Dirty {
+		t.Fatal("Expected dirty repository")
+	}
+	if Get(dir, false).Dirty {
+		t.Fatal("Dirty repository should only be detected when requested")
+	}
+}
+
+func TestGetNotRepo(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	// don't find repository in parent directories
+	t.Setenv("GIT_CEILING_DIRECTORIES", os.TempDir())
+	info := Get(t.TempDir(), true)
+	if info != (Info{}) {
+		t.Fatalf("Expected empty info outside of git repository, got: %+v", info)
+	}
+}
+
+func TestGetTimeout(t *testing.T) {
+	dir := gitInit(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	info := get(ctx, dir, true)
+	if info != (Info{}) {
+		t.Fatalf("Expected empty info after timeout, got: %+v", info)
+	}
+}
@@ -16,11 +16,15 @@ type Histcli struct {
 
 // New Histcli
 func New(sugar *zap.SugaredLogger) Histcli {
-	return Histcli{}
+	return Histcli{sugar: sugar}
 }
 
 // AddRecord to the histcli
-func (h *Histcli) AddRecord(rec *record.V1) {
+// Deleted records are skipped
+func (h *Histcli) AddRecord(rec *record.V2) {
+	if rec.Deleted {
+		return
+	}
 	cli := recordint.NewSearchApp(h.sugar, rec)
 
 	h.List = append(h.List, cli)
@@ -32,3 +36,26 @@ func (h *Histcli) AddCmdLine(cmdline string) {
 
 	h.List = append(h.List, cli)
 }
+
+// Flag records with given IDs
+// Records flagged as deleted are removed from the histcli
+func (h *Histcli) Flag(flag recordint.Flag, ids map[string]bool) {
+	var list []recordint.SearchApp
+	for _, rec := range h.List {
+		if rec.IsRaw || !ids[rec.RecordID] {
+			list = append(list, rec)
+			continue
+		}
+		switch flag.Name {
+		case recordint.FlagDeleted:
+			if flag.Value {
+				// drop the record
+				continue
+			}
+		case recordint.FlagFavorite:
+			rec.Favorite = flag.Value
+		}
+		list = append(list, rec)
+	}
+	h.List = list
+}
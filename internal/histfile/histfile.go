@@ -6,265 +6,285 @@ import (
 	"strconv"
 	"sync"
 
-	"github.com/curusarn/resh/internal/histcli"
+	"github.com/curusarn/resh/internal/bus"
+	"github.com/curusarn/resh/internal/histio"
 	"github.com/curusarn/resh/internal/histlist"
-	"github.com/curusarn/resh/internal/recio"
 	"github.com/curusarn/resh/internal/recordint"
 	"github.com/curusarn/resh/internal/records"
 	"github.com/curusarn/resh/internal/recutil"
+	"github.com/curusarn/resh/internal/redact"
 	"github.com/curusarn/resh/record"
 	"go.uber.org/zap"
 )
 
-// TODO: get rid of histfile - use histio instead
-// Histfile writes records to histfile
+// Histfile merges records and writes them to history using histio
 type Histfile struct {
 	sugar *zap.SugaredLogger
 
 	sessionsMutex sync.Mutex
-	sessions      map[string]recordint.Collect
-	historyPath   string
+	// first parts of records waiting for the second part
+	sessions map[string]recordint.Collect
+	// second parts that arrived before their first part
+	earlyParts map[string]recordint.Collect
 
 	// NOTE: we have separate histories which only differ if there was not enough resh_history
-	//			resh_history itself is common for both bash and zsh
-	bashCmdLines histlist.Histlist
-	zshCmdLines  histlist.Histlist
+	//			resh_history itself is common for bash, zsh and fish
+	// records are written from both writer and sessionGC
+	cmdLinesMutex sync.Mutex
+	bashCmdLines  histlist.Histlist
+	zshCmdLines   histlist.Histlist
+	fishCmdLines  histlist.Histlist
 
-	cliRecords histcli.Histcli
+	hio      *histio.Histio
+	redactor *redact.Redactor
 
-	rio *recio.RecIO
+	// gets merged records after they are written
+	merged *bus.Bus[record.V2]
 }
 
 // New creates new histfile and runs its goroutines
-func New(sugar *zap.SugaredLogger, input chan recordint.Collect, sessionsToDrop chan string,
-	reshHistoryPath string, bashHistoryPath string, zshHistoryPath string,
+func New(sugar *zap.SugaredLogger, input <-chan recordint.Collect, sessionsToDrop <-chan string,
+	merged *bus.Bus[record.V2],
+	hio *histio.Histio, redactor *redact.Redactor, bashHistoryPath, zshHistoryPath, fishHistoryPath string,
 	maxInitHistSize int, minInitHistSizeKB int,
 	signals chan os.Signal, shutdownDone chan string) *Histfile {
 
-	rio := recio.New(sugar.With("module", "histfile"))
 	hf := Histfile{
 		sugar:        sugar.With("module", "histfile"),
 		sessions:     map[string]recordint.Collect{},
-		historyPath:  reshHistoryPath,
+		earlyParts:   map[string]recordint.Collect{},
 		bashCmdLines: histlist.New(sugar),
 		zshCmdLines:  histlist.New(sugar),
-		cliRecords:   histcli.New(sugar),
-		rio:          &rio,
+		fishCmdLines: histlist.New(sugar),
+		hio:          hio,
+		redactor:     redactor,
+
+		merged: merged,
 	}
-	go hf.loadHistory(bashHistoryPath, zshHistoryPath, maxInitHistSize, minInitHistSizeKB)
+	go hf.loadHistory(bashHistoryPath, zshHistoryPath, fishHistoryPath, maxInitHistSize, minInitHistSizeKB)
 	go hf.writer(input, signals, shutdownDone)
 	go hf.sessionGC(sessionsToDrop)
 	return &hf
 }
 
-// load records from resh history, reverse, enrich and save
-func (h *Histfile) loadCliRecords(recs []record.V1) {
-	for _, cmdline := range h.bashCmdLines.List {
-		h.cliRecords.AddCmdLine(cmdline)
-	}
-	for _, cmdline := range h.zshCmdLines.List {
-		h.cliRecords.AddCmdLine(cmdline)
-	}
-	for i := len(recs) - 1; i >= 0; i-- {
-		rec := recs[i]
-		h.cliRecords.AddRecord(&rec)
+// loadsHistory from resh histories and if there is not enough of it also load native shell histories
+func (h *Histfile) loadHistory(bashHistoryPath, zshHistoryPath, fishHistoryPath string, maxInitHistSize, minInitHistSizeKB int) {
+	h.sugar.Debugw("Loading resh history from files ...")
+	err := h.hio.Load()
+	if err != nil {
+		h.sugar.Fatalf("Failed to load history: %v", err)
 	}
-	h.sugar.Infow("RESH history loaded",
-		"historyRecordsCount", len(h.cliRecords.List),
+	h.sugar.Infow("RESH history loaded from files",
+		"recordCount", len(h.hio.Records()),
 	)
-}
-
-// loadsHistory from resh_history and if there is not enough of it also load native shell histories
-func (h *Histfile) loadHistory(bashHistoryPath, zshHistoryPath string, maxInitHistSize, minInitHistSizeKB int) {
 	h.sugar.Infow("Checking if resh_history is large enough ...")
-	fi, err := os.Stat(h.historyPath)
-	var size int
-	if err != nil {
-		h.sugar.Errorw("Failed to stat resh_history file", "error", err)
-	} else {
-		size = int(fi.Size())
-	}
+	size := int(h.hio.Size())
 	useNativeHistories := false
+	var bashCmdLines, zshCmdLines, fishCmdLines histlist.Histlist
 	if size/1024 < minInitHistSizeKB {
 		useNativeHistories = true
-		h.sugar.Warnw("RESH history is too small - loading native bash and zsh history ...")
-		h.bashCmdLines = records.LoadCmdLinesFromBashFile(h.sugar, bashHistoryPath)
-		h.sugar.Infow("Bash history loaded", "cmdLineCount", len(h.bashCmdLines.List))
-		h.zshCmdLines = records.LoadCmdLinesFromZshFile(h.sugar, zshHistoryPath)
-		h.sugar.Infow("Zsh history loaded", "cmdLineCount", len(h.zshCmdLines.List))
+		h.sugar.Warnw("RESH history is too small - loading native bash, zsh and fish history ...")
+		bashCmdLines = records.LoadCmdLinesFromBashFile(h.sugar, bashHistoryPath)
+		h.sugar.Infow("Bash history loaded", "cmdLineCount", len(bashCmdLines.List))
+		zshCmdLines = records.LoadCmdLinesFromZshFile(h.sugar, zshHistoryPath)
+		h.sugar.Infow("Zsh history loaded", "cmdLineCount", len(zshCmdLines.List))
+		fishCmdLines = records.LoadCmdLinesFromFishFile(h.sugar, fishHistoryPath)
+		h.sugar.Infow("Fish history loaded", "cmdLineCount", len(fishCmdLines.List))
+		h.hio.AddCmdLines(bashCmdLines.List)
+		h.hio.AddCmdLines(zshCmdLines.List)
+		h.hio.AddCmdLines(fishCmdLines.List)
 		// no maxInitHistSize when using native histories
 		maxInitHistSize = math.MaxInt32
 	}
-	h.sugar.Debugw("Loading resh history from file ...",
-		"historyFile", h.historyPath,
-	)
-	history, err := h.rio.ReadAndFixFile(h.historyPath, 3)
-	if err != nil {
-		h.sugar.Fatalf("Failed to read history file: %v", err)
-	}
-	h.sugar.Infow("RESH history loaded from file",
-		"historyFile", h.historyPath,
-		"recordCount", len(history),
-	)
-	go h.loadCliRecords(history)
+
+	// records written from now on are added to cmdLines by writeRecord
+	h.cmdLinesMutex.Lock()
+	defer h.cmdLinesMutex.Unlock()
 	// NOTE: keeping this weird interface for now because we might use it in the future
 	//       when we only load bash or zsh history
-	reshCmdLines := loadCmdLines(h.sugar, history)
+	reshCmdLines := loadCmdLines(h.sugar, h.hio.Records())
 	h.sugar.Infow("RESH history loaded and processed",
 		"recordCount", len(reshCmdLines.List),
 	)
 	if !useNativeHistories {
 		h.bashCmdLines = reshCmdLines
 		h.zshCmdLines = histlist.Copy(reshCmdLines)
+		h.fishCmdLines = histlist.Copy(reshCmdLines)
 		return
 	}
+	h.bashCmdLines = bashCmdLines
+	h.zshCmdLines = zshCmdLines
+	h.fishCmdLines = fishCmdLines
 	h.bashCmdLines.AddHistlist(reshCmdLines)
 	h.sugar.Infow("Processed bash history and resh history together", "cmdLinecount", len(h.bashCmdLines.List))
 	h.zshCmdLines.AddHistlist(reshCmdLines)
 	h.sugar.Infow("Processed zsh history and resh history together", "cmdLineCount", len(h.zshCmdLines.List))
+	h.fishCmdLines.AddHistlist(reshCmdLines)
+	h.sugar.Infow("Processed fish history and resh history together", "cmdLineCount", len(h.fishCmdLines.List))
 }
 
-// sessionGC reads sessionIDs from channel and deletes them from histfile struct
-func (h *Histfile) sessionGC(sessionsToDrop chan string) {
-	for {
+// sessionGC reads sessionIDs from channel and writes and deletes their hanging parts
+func (h *Histfile) sessionGC(sessionsToDrop <-chan string) {
+	for session := range sessionsToDrop {
+		sugar := h.sugar.With("sessionID", session)
+		sugar.Debugw("Got session to drop")
+		var hanging []record.V2
 		func() {
-			session := <-sessionsToDrop
-			sugar := h.sugar.With("sessionID", session)
-			sugar.Debugw("Got session to drop")
 			h.sessionsMutex.Lock()
 			defer h.sessionsMutex.Unlock()
-			if part1, found := h.sessions[session]; found == true {
-				sugar.Infow("Dropping session")
-				delete(h.sessions, session)
-				go h.rio.AppendToFile(h.historyPath, []record.V1{part1.Rec})
-			} else {
-				sugar.Infow("No hanging parts for session - nothing to drop")
+			for mergeID, part1 := range h.sessions {
+				if part1.SessionID == session {
+					delete(h.sessions, mergeID)
+					hanging = append(hanging, part1.Rec)
+				}
+			}
+			for mergeID, part2 := range h.earlyParts {
+				if part2.SessionID == session {
+					delete(h.earlyParts, mergeID)
+				}
 			}
 		}()
+		if len(hanging) == 0 {
+			sugar.Infow("No hanging parts for session - nothing to drop")
+			continue
+		}
+		sugar.Infow("Dropping session", "hangingRecords", len(hanging))
+		for _, rec := range hanging {
+			h.writeRecord(sugar, rec)
+		}
 	}
 }
 
 // writer reads records from channel, merges them and writes them to file
-func (h *Histfile) writer(collect chan recordint.Collect, signals chan os.Signal, shutdownDone chan string) {
+// Records are merged and written one by one to keep them in order
+func (h *Histfile) writer(collect <-chan recordint.Collect, signals chan os.Signal, shutdownDone chan string) {
 	for {
-		func() {
-			select {
-			case rec := <-collect:
-				part := "2"
-				if rec.Rec.PartOne {
-					part = "1"
-				}
-				sugar := h.sugar.With(
-					"recordCmdLine", rec.Rec.CmdLine,
-					"recordPart", part,
-					"recordShell", rec.Shell,
-				)
-				sugar.Debugw("Got record")
-				h.sessionsMutex.Lock()
-				defer h.sessionsMutex.Unlock()
+		select {
+		case rec := <-collect:
+			part := "2"
+			if rec.Rec.PartOne {
+				part = "1"
+			}
+			sugar := h.sugar.With(
+				"recordCmdLine", rec.Rec.CmdLine,
+				"recordPart", part,
+				"recordShell", rec.Shell,
+			)
+			sugar.Debugw("Got record")
+			if part1, part2, ok := h.match(sugar, rec); ok {
+				h.mergeAndWriteRecord(sugar, part1, part2)
+			}
+		case sig := <-signals:
+			sugar := h.sugar.With(
+				"signal", sig.String(),
+			)
+			sugar.Infow("Got signal")
+			h.sessionsMutex.Lock()
+			defer h.sessionsMutex.Unlock()
+			sugar.Debugw("Unlocked mutex")
 
-				// allows nested sessions to merge records properly
-				mergeID := rec.SessionID + "_" + strconv.Itoa(rec.Shlvl)
-				sugar = sugar.With("mergeID", mergeID)
-				if rec.Rec.PartOne {
-					if _, found := h.sessions[mergeID]; found {
-						msg := "Got another first part of the records before merging the previous one - overwriting!"
-						if rec.Shell == "zsh" {
-							sugar.Warnw(msg)
-						} else {
-							sugar.Infow(msg + " Unfortunately this is normal in bash, it can't be prevented.")
-						}
-					}
-					h.sessions[mergeID] = rec
-				} else {
-					if part1, found := h.sessions[mergeID]; found == false {
-						sugar.Warnw("Got second part of record and nothing to merge it with - ignoring!")
-					} else {
-						delete(h.sessions, mergeID)
-						go h.mergeAndWriteRecord(sugar, part1, rec)
-					}
-				}
-			case sig := <-signals:
-				sugar := h.sugar.With(
-					"signal", sig.String(),
+			for sessID, rec := range h.sessions {
+				sugar.Warnw("Writing incomplete record for session",
+					"sessionID", sessID,
 				)
-				sugar.Infow("Got signal")
-				h.sessionsMutex.Lock()
-				defer h.sessionsMutex.Unlock()
-				sugar.Debugw("Unlocked mutex")
+				h.writeRecord(sugar, rec.Rec)
+			}
+			sugar.Debugw("Shutdown successful")
+			shutdownDone <- "histfile"
+			return
+		}
+	}
+}
 
-				for sessID, rec := range h.sessions {
-					sugar.Warnw("Writing incomplete record for session",
-						"sessionID", sessID,
-					)
-					h.writeRecord(sugar, rec.Rec)
-				}
-				sugar.Debugw("Shutdown successful")
-				shutdownDone <- "histfile"
-				return
+// match returns both parts of the record once they are both here
+func (h *Histfile) match(sugar *zap.SugaredLogger, rec recordint.Collect) (recordint.Collect, recordint.Collect, bool) {
+	h.sessionsMutex.Lock()
+	defer h.sessionsMutex.Unlock()
+
+	// allows nested sessions to merge records properly
+	mergeID := rec.SessionID + "_" + strconv.Itoa(rec.Shlvl)
+	sugar = sugar.With("mergeID", mergeID)
+	if rec.Rec.PartOne {
+		if part2, found := h.earlyParts[mergeID]; found && part2.Rec.RecordID == rec.Rec.RecordID {
+			sugar.Infow("Got first part of record after the second part - merging")
+			delete(h.earlyParts, mergeID)
+			return rec, part2, true
+		}
+		if _, found := h.sessions[mergeID]; found {
+			msg := "Got another first part of the records before merging the previous one - overwriting!"
+			if rec.Shell == "zsh" {
+				sugar.Warnw(msg)
+			} else {
+				sugar.Infow(msg + " Unfortunately this is normal in bash, it can't be prevented.")
 			}
-		}()
+		}
+		h.sessions[mergeID] = rec
+		return recordint.Collect{}, recordint.Collect{}, false
+	}
+	part1, found := h.sessions[mergeID]
+	if !found || part1.Rec.RecordID != rec.Rec.RecordID {
+		// this is expected for commands ignored by resh-collect
+		// the first part can also still be on its way - keep the latest second part around in case it is
+		sugar.Infow("Got second part of record and nothing to merge it with - keeping it for later")
+		h.earlyParts[mergeID] = rec
+		return recordint.Collect{}, recordint.Collect{}, false
 	}
+	delete(h.sessions, mergeID)
+	return part1, rec, true
 }
 
-func (h *Histfile) writeRecord(sugar *zap.SugaredLogger, rec record.V1) {
-	h.rio.AppendToFile(h.historyPath, []record.V1{rec})
+// redact secrets in the record - returns false if the record should be dropped
+func (h *Histfile) redact(sugar *zap.SugaredLogger, rec *record.V2) bool {
+	cmdLine, keep := h.redactor.CmdLine(rec.CmdLine)
+	if !keep {
+		sugar.Infow("Dropping record because it contains secrets")
+		return false
+	}
+	if cmdLine != rec.CmdLine {
+		sugar.Infow("Secrets redacted from record")
+		rec.CmdLine = cmdLine
+	}
+	return true
 }
 
-func (h *Histfile) mergeAndWriteRecord(sugar *zap.SugaredLogger, part1 recordint.Collect, part2 recordint.Collect) {
-	rec, err := recutil.Merge(&part1, &part2)
-	if err != nil {
-		sugar.Errorw("Error while merging records", "error", err)
+// writeRecord writes the record to history and publishes it - all records are written through here
+func (h *Histfile) writeRecord(sugar *zap.SugaredLogger, rec record.V2) {
+	if !h.redact(sugar, &rec) {
 		return
 	}
-
-	recV1 := record.V1(rec)
 	func() {
-		cmdLine := rec.CmdLine
-		h.bashCmdLines.AddCmdLine(cmdLine)
-		h.zshCmdLines.AddCmdLine(cmdLine)
-		h.cliRecords.AddRecord(&recV1)
+		h.cmdLinesMutex.Lock()
+		defer h.cmdLinesMutex.Unlock()
+		h.bashCmdLines.AddCmdLine(rec.CmdLine)
+		h.zshCmdLines.AddCmdLine(rec.CmdLine)
+		h.fishCmdLines.AddCmdLine(rec.CmdLine)
 	}()
-
-	h.rio.AppendToFile(h.historyPath, []record.V1{recV1})
+	err := h.hio.Append(&rec)
+	if err != nil {
+		sugar.Errorw("Error while writing record", "error", err)
+		return
+	}
+	h.merged.Publish(rec)
 }
 
-// TODO: use errors in RecIO
-// func writeRecord(sugar *zap.SugaredLogger, rec record.V1, outputPath string) {
-// 	recJSON, err := json.Marshal(rec)
-// 	if err != nil {
-// 		sugar.Errorw("Marshalling error", "error", err)
-// 		return
-// 	}
-// 	f, err := os.OpenFile(outputPath,
-// 		os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-// 	if err != nil {
-// 		sugar.Errorw("Could not open file", "error", err)
-// 		return
-// 	}
-// 	defer f.Close()
-// 	_, err = f.Write(append(recJSON, []byte("\n")...))
-// 	if err != nil {
-// 		sugar.Errorw("Error while writing record",
-// 			"recordRaw", rec,
-// 			"error", err,
-// 		)
-// 		return
-// 	}
-// }
-
-// DumpCliRecords returns enriched records
-func (h *Histfile) DumpCliRecords() histcli.Histcli {
-	// don't forget locks in the future
-	return h.cliRecords
+func (h *Histfile) mergeAndWriteRecord(sugar *zap.SugaredLogger, part1 recordint.Collect, part2 recordint.Collect) {
+	rec, err := recutil.Merge(&part1, &part2)
+	if err != nil {
+		sugar.Errorw("Error while merging records", "error", err)
+		return
+	}
+	h.writeRecord(sugar, rec)
 }
 
-func loadCmdLines(sugar *zap.SugaredLogger, recs []record.V1) histlist.Histlist {
+func loadCmdLines(sugar *zap.SugaredLogger, recs []record.V2) histlist.Histlist {
 	hl := histlist.New(sugar)
 	// go from bottom and deduplicate
 	var cmdLines []string
 	cmdLinesSet := map[string]bool{}
 	for i := len(recs) - 1; i >= 0; i-- {
+		if recs[i].Deleted {
+			continue
+		}
 		cmdLine := recs[i].CmdLine
 		if cmdLinesSet[cmdLine] {
 			continue
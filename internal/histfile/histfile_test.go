@@ -0,0 +1,104 @@
+package histfile
+
+import (
+	"testing"
+
+	"github.com/curusarn/resh/internal/bus"
+	"github.com/curusarn/resh/internal/cfg"
+	"github.com/curusarn/resh/internal/histio"
+	"github.com/curusarn/resh/internal/histlist"
+	"github.com/curusarn/resh/internal/recordint"
+	"github.com/curusarn/resh/internal/redact"
+	"github.com/curusarn/resh/record"
+	"go.uber.org/zap"
+)
+
+func newTestHistfile() *Histfile {
+	return &Histfile{
+		sugar:      zap.NewNop().Sugar(),
+		sessions:   map[string]recordint.Collect{},
+		earlyParts: map[string]recordint.Collect{},
+	}
+}
+
+func part(recordID string, partOne bool) recordint.Collect {
+	return recordint.Collect{
+		SessionID: "s",
+		Shlvl:     1,
+		Rec:       record.V2{SessionID: "s", RecordID: recordID, PartOne: partOne},
+	}
+}
+
+func TestMatch(t *testing.T) {
+	h := newTestHistfile()
+	sugar := h.sugar
+
+	if _, _, ok := h.match(sugar, part("r1", true)); ok {
+		t.Fatal("First part alone should not match")
+	}
+	part1, part2, ok := h.match(sugar, part("r1", false))
+	if !ok || !part1.Rec.PartOne || part2.Rec.PartOne || part1.Rec.RecordID != "r1" {
+		t.Fatalf("Parts should match: %+v %+v", part1, part2)
+	}
+
+	// second part of an ignored command
+	if _, _, ok := h.match(sugar, part("ignored", false)); ok {
+		t.Fatal("Second part alone should not match")
+	}
+	// second part arrives before the first part
+	if _, _, ok := h.match(sugar, part("r2", false)); ok {
+		t.Fatal("Second part alone should not match")
+	}
+	part1, part2, ok = h.match(sugar, part("r2", true))
+	if !ok || part1.Rec.RecordID != "r2" || part2.Rec.RecordID != "r2" || !part1.Rec.PartOne {
+		t.Fatalf("Parts arriving out of order should match: %+v %+v", part1, part2)
+	}
+	if len(h.sessions) != 0 || len(h.earlyParts) != 0 {
+		t.Fatalf("Matched parts should be removed: %+v %+v", h.sessions, h.earlyParts)
+	}
+
+	// second part of a different record doesn't match
+	h.match(sugar, part("r3", true))
+	if _, _, ok := h.match(sugar, part("r4", false)); ok {
+		t.Fatal("Parts of different records should not match")
+	}
+	if _, _, ok := h.match(sugar, part("r3", false)); !ok {
+		t.Fatal("First part should still wait for its second part")
+	}
+}
+
+func TestDroppedSessionIsPublished(t *testing.T) {
+	h := newTestHistfile()
+	h.hio = histio.New(h.sugar, t.TempDir(), "this")
+	if err := h.hio.Load(); err != nil {
+		t.Fatalf("Unexpected error during load: %v", err)
+	}
+	redactor, err := redact.New(cfg.Redaction{})
+	if err != nil {
+		t.Fatalf("Test setup failed: %v", err)
+	}
+	h.redactor = redactor
+	h.merged = bus.New[record.V2](h.sugar, "merged")
+	merged := h.merged.Subscribe("test", 10)
+	h.bashCmdLines = histlist.New(h.sugar)
+	h.zshCmdLines = histlist.New(h.sugar)
+	h.fishCmdLines = histlist.New(h.sugar)
+
+	rec := part("r1", true)
+	rec.Rec.CmdLine = "sleep 100"
+	h.match(h.sugar, rec)
+	sessionsToDrop := make(chan string, 1)
+	sessionsToDrop <- "s"
+	close(sessionsToDrop)
+	h.sessionGC(sessionsToDrop)
+
+	if len(h.hio.Records()) != 1 {
+		t.Fatalf("Expected record of dropped session to be written, got %d records", len(h.hio.Records()))
+	}
+	if len(merged) != 1 || (<-merged).CmdLine != "sleep 100" {
+		t.Fatal("Expected record of dropped session to be published")
+	}
+	if len(h.bashCmdLines.List) != 1 || h.bashCmdLines.List[0] != "sleep 100" {
+		t.Fatalf("Expected record of dropped session in cmdLines, got %v", h.bashCmdLines.List)
+	}
+}
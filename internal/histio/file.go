@@ -1,49 +1,101 @@
 package histio
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"sync"
 
 	"github.com/curusarn/resh/internal/recio"
+	"github.com/curusarn/resh/internal/recordint"
 	"github.com/curusarn/resh/record"
 	"go.uber.org/zap"
 )
 
 type histfile struct {
 	sugar *zap.SugaredLogger
-	// deviceID string
-	path string
+	path  string
+	// index allows fast loading of the history file - see index.go
+	indexPath string
 
 	mu       sync.RWMutex
-	data     []record.V1
+	data     []record.V2
 	fileinfo os.FileInfo
 }
 
-func newHistfile(sugar *zap.SugaredLogger, path string) *histfile {
+func newHistfile(sugar *zap.SugaredLogger, path, indexPath string) *histfile {
 	return &histfile{
 		sugar: sugar.With(
 			// FIXME: drop V1 once original histfile is gone
 			"component", "histfileV1",
 			"path", path,
 		),
-		// deviceID: deviceID,
-		path: path,
+		path:      path,
+		indexPath: indexPath,
 	}
 }
 
-func (h *histfile) updateFromFile() error {
+// updateFromFile reads the whole file and replaces data with its contents
+// fix controls if the file can be rewritten without records that could not be decoded
+// we only want to fix history of this device - other histories are not ours to modify
+// Appends wait until the file is read - records appended meanwhile would be lost from data
+func (h *histfile) updateFromFile(fix bool) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	// stat before reading - index of file that changed during reading ends up stale which is safe
+	info, err := os.Stat(h.path)
+	if err != nil {
+		return fmt.Errorf("history file not found: %w", err)
+	}
+	newData, err := readIndex(h.indexPath, info)
+	if err == nil {
+		h.sugar.Infow("Loaded history records from index",
+			"recordCount", len(newData),
+		)
+		h.data = newData
+		h.fileinfo = info
+		return nil
+	}
+	if errors.Is(err, errIndexStale) || errors.Is(err, fs.ErrNotExist) {
+		h.sugar.Infow("History index is missing or outdated - reading history file", "reason", err)
+	} else {
+		h.sugar.Warnw("Could not read history index - reading history file", zap.Error(err))
+	}
+
 	rio := recio.New(h.sugar)
-	// TODO: decide and handle errors
-	newData, _, err := rio.ReadFile(h.path)
+	if fix {
+		newData, err = rio.ReadAndFixFile(h.path, 3)
+	} else {
+		// decoding errors are logged by recio
+		newData, _, err = rio.ReadFile(h.path)
+	}
 	if err != nil {
 		return fmt.Errorf("could not read history file: %w", err)
 	}
-	h.mu.Lock()
-	defer h.mu.Unlock()
 	h.data = newData
-	h.updateFileInfo()
-	return nil
+	h.saveIndex(info)
+	return h.updateFileInfo()
+}
+
+// saveIndex writes the whole index - failure is not fatal, the history file is read next time
+func (h *histfile) saveIndex(info os.FileInfo) {
+	err := writeIndex(h.indexPath, info, h.data)
+	if err != nil {
+		h.sugar.Warnw("Could not write history index", zap.Error(err))
+	}
+}
+
+// modifiedExternally returns true if the file changed since we last read or wrote it (e.g. history import)
+func (h *histfile) modifiedExternally() bool {
+	if h.fileinfo == nil {
+		return true
+	}
+	info, err := os.Stat(h.path)
+	if err != nil {
+		return true
+	}
+	return !newIndexHeader(h.fileinfo, 0).matches(info)
 }
 
 func (h *histfile) updateFileInfo() error {
@@ -54,3 +106,100 @@ func (h *histfile) updateFileInfo() error {
 	h.fileinfo = info
 	return nil
 }
+
+func (h *histfile) append(recs []record.V2) error {
+	rio := recio.New(h.sugar)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	// records written by someone else are not in our data - index must not claim to contain them
+	keepIndex := !h.modifiedExternally()
+	prevInfo := h.fileinfo
+	err := rio.AppendToFile(h.path, recs)
+	if err != nil {
+		return err
+	}
+	h.data = append(h.data, recs...)
+	err = h.updateFileInfo()
+	if err != nil {
+		return err
+	}
+	if !keepIndex {
+		h.sugar.Infow("History file was modified by another process - index will be rebuilt on next load")
+		return nil
+	}
+	err = appendToIndex(h.indexPath, prevInfo, h.fileinfo, recs)
+	if err != nil {
+		// stale index is detected and rebuilt on next load
+		h.sugar.Infow("Could not append records to history index", zap.Error(err))
+	}
+	return nil
+}
+
+func (h *histfile) size() int64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.fileinfo == nil {
+		return 0
+	}
+	return h.fileinfo.Size()
+}
+
+func (h *histfile) records() []record.V2 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	recs := make([]record.V2, len(h.data))
+	copy(recs, h.data)
+	return recs
+}
+
+// flag sets the flag on matching records and rewrites the file if any record was changed
+// Returns the records whose flag changed
+func (h *histfile) flag(flag recordint.Flag) ([]record.V2, error) {
+	rio := recio.New(h.sugar)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	// the file could have been appended to by someone else (e.g. history import)
+	// we would lose such records when overwriting the file with our data
+	data, decodeErrs, err := rio.ReadFile(h.path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read history file: %w", err)
+	}
+	if len(decodeErrs) > 0 {
+		// overwriting the file would drop lines that could not be decoded
+		return nil, fmt.Errorf("history file contains %d invalid lines - refusing to rewrite it", len(decodeErrs))
+	}
+	h.data = data
+	var changed []record.V2
+	for i := range h.data {
+		rec := &h.data[i]
+		if !flag.Matches(rec.CmdLine) {
+			continue
+		}
+		switch flag.Name {
+		case recordint.FlagDeleted:
+			if rec.Deleted == flag.Value {
+				continue
+			}
+			rec.Deleted = flag.Value
+		case recordint.FlagFavorite:
+			if rec.Favorite == flag.Value {
+				continue
+			}
+			rec.Favorite = flag.Value
+		}
+		changed = append(changed, *rec)
+	}
+	if len(changed) == 0 {
+		return nil, nil
+	}
+	err = rio.OverwriteFile(h.path, h.data)
+	if err != nil {
+		return nil, fmt.Errorf("could not write flagged records: %w", err)
+	}
+	err = h.updateFileInfo()
+	if err != nil {
+		return nil, err
+	}
+	h.saveIndex(h.fileinfo)
+	return changed, nil
+}
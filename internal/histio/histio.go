@@ -1,43 +1,323 @@
 package histio
 
 import (
+	"fmt"
+	"os"
 	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 
+	"github.com/curusarn/resh/internal/datadir"
+	"github.com/curusarn/resh/internal/futil"
+	"github.com/curusarn/resh/internal/histcli"
+	"github.com/curusarn/resh/internal/recordint"
 	"github.com/curusarn/resh/record"
 	"go.uber.org/zap"
 )
 
+// Histio manages history files of all devices
+// Records of this device are written to '<dataDir>/history/<deviceID>'
+// History files of other devices (e.g. copied or synced from other machines) are
+// discovered in the same directory and merged into the search data
 type Histio struct {
-	sugar   *zap.SugaredLogger
-	histDir string
+	sugar    *zap.SugaredLogger
+	histDir  string
+	indexDir string
 
 	thisDeviceID string
 	thisHistory  *histfile
-	// TODO: remote histories
-	// moreHistories map[string]*histfile
+	// histories of other devices are added while loading
+	historiesMutex sync.RWMutex
+	moreHistories  map[string]*histfile
 
-	recordsToAppend chan record.V1
-	// recordsToFlag   chan recordint.Flag
+	// search data for all histories
+	cliMutex   sync.RWMutex
+	cliRecords histcli.Histcli
+	// records are only added to search data after all histories are loaded into it
+	cliLoaded bool
+}
+
+// GetPath returns path to history file of given device
+func GetPath(dataDir, deviceID string) string {
+	return path.Join(dataDir, datadir.HistoryDirName, deviceID)
+}
+
+// GetPaths returns paths to history files of all devices
+func GetPaths(dataDir string) ([]string, error) {
+	histDir := path.Join(dataDir, datadir.HistoryDirName)
+	deviceIDs, err := discoverDevices(histDir)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, deviceID := range deviceIDs {
+		paths = append(paths, path.Join(histDir, deviceID))
+	}
+	return paths, nil
 }
 
 func New(sugar *zap.SugaredLogger, dataDir, deviceID string) *Histio {
 	sugarHistio := sugar.With(zap.String("component", "histio"))
-	histDir := path.Join(dataDir, "history")
-	currPath := path.Join(histDir, deviceID)
-	// TODO: file extension for the history, yes or no? (<id>.reshjson vs. <id>)
-
-	// TODO: discover other history files, exclude current
+	histDir := path.Join(dataDir, datadir.HistoryDirName)
+	indexDir := path.Join(dataDir, datadir.IndexDirName)
+	currPath := GetPath(dataDir, deviceID)
 
 	return &Histio{
-		sugar:   sugarHistio,
-		histDir: histDir,
+		sugar:    sugarHistio,
+		histDir:  histDir,
+		indexDir: indexDir,
 
-		thisDeviceID: deviceID,
-		thisHistory:  newHistfile(sugar, currPath),
-		// moreHistories: ...
+		thisDeviceID:  deviceID,
+		thisHistory:   newHistfile(sugar, currPath, path.Join(indexDir, deviceID)),
+		moreHistories: map[string]*histfile{},
+		cliRecords:    histcli.New(sugar),
 	}
 }
 
-func (h *Histio) Append(r *record.V1) {
+// Load history of this device and discover and load histories of other devices
+// Histories of other devices that fail to load are skipped
+func (h *Histio) Load() error {
+	err := os.MkdirAll(h.histDir, 0755)
+	if err != nil {
+		return fmt.Errorf("could not create history directory: %w", err)
+	}
+	_, err = futil.TouchFile(h.thisHistory.path)
+	if err != nil {
+		return fmt.Errorf("could not create history file: %w", err)
+	}
+	err = h.thisHistory.updateFromFile(true)
+	if err != nil {
+		return fmt.Errorf("could not load history of this device: %w", err)
+	}
 
+	deviceIDs, err := discoverDevices(h.histDir)
+	if err != nil {
+		return fmt.Errorf("could not discover histories of other devices: %w", err)
+	}
+	for _, deviceID := range deviceIDs {
+		if deviceID == h.thisDeviceID {
+			continue
+		}
+		hf := newHistfile(h.sugar, path.Join(h.histDir, deviceID), path.Join(h.indexDir, deviceID))
+		err := hf.updateFromFile(false)
+		if err != nil {
+			h.sugar.Errorw("Failed to load history of other device - skipping it",
+				"deviceID", deviceID,
+				zap.Error(err),
+			)
+			continue
+		}
+		h.historiesMutex.Lock()
+		h.moreHistories[deviceID] = hf
+		h.historiesMutex.Unlock()
+	}
+	h.sugar.Infow("Histories loaded",
+		"deviceCount", len(h.otherHistories())+1,
+	)
+
+	// records appended from now on are added to search data by Append
+	h.cliMutex.Lock()
+	defer h.cliMutex.Unlock()
+	recs := h.searchRecords()
+	// newest records first
+	for i := len(recs) - 1; i >= 0; i-- {
+		h.cliRecords.AddRecord(&recs[i])
+	}
+	h.cliLoaded = true
+	return nil
+}
+
+// discoverDevices returns IDs of devices that have a history file in the history directory
+// Files with extension (e.g. backups) and hidden files are not history files
+func discoverDevices(histDir string) ([]string, error) {
+	entries, err := os.ReadDir(histDir)
+	if err != nil {
+		return nil, err
+	}
+	var deviceIDs []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.Contains(name, ".") {
+			continue
+		}
+		deviceIDs = append(deviceIDs, name)
+	}
+	return deviceIDs, nil
+}
+
+// otherHistories returns loaded histories of other devices
+func (h *Histio) otherHistories() []*histfile {
+	h.historiesMutex.RLock()
+	defer h.historiesMutex.RUnlock()
+	histories := make([]*histfile, 0, len(h.moreHistories))
+	for _, hf := range h.moreHistories {
+		histories = append(histories, hf)
+	}
+	return histories
+}
+
+// Records returns records from all histories ordered by time (oldest first)
+func (h *Histio) Records() []record.V2 {
+	recs := h.thisHistory.records()
+	histories := h.otherHistories()
+	if len(histories) == 0 {
+		return recs
+	}
+	for _, hf := range histories {
+		recs = append(recs, hf.records()...)
+	}
+	sortByTime(recs)
+	return recs
+}
+
+// searchRecords returns records for search ordered by time (oldest first)
+// Commands deleted on this device are hidden in histories of other devices as well - see Flag
+func (h *Histio) searchRecords() []record.V2 {
+	recs := h.thisHistory.records()
+	deleted := map[string]bool{}
+	for _, rec := range recs {
+		if rec.Deleted {
+			deleted[recordint.TrimCmdLine(rec.CmdLine)] = true
+		}
+	}
+	for _, hf := range h.otherHistories() {
+		for _, rec := range hf.records() {
+			if !deleted[recordint.TrimCmdLine(rec.CmdLine)] {
+				recs = append(recs, rec)
+			}
+		}
+	}
+	sortByTime(recs)
+	return recs
+}
+
+// Size returns combined size of all history files in bytes
+func (h *Histio) Size() int64 {
+	size := h.thisHistory.size()
+	for _, hf := range h.otherHistories() {
+		size += hf.size()
+	}
+	return size
+}
+
+// Append record to history of this device and add it to search data
+// Records appended before search data is loaded get there from the history during Load
+func (h *Histio) Append(r *record.V2) error {
+	// held during append so that Load can't see the record in history and add it to search data as well
+	h.cliMutex.Lock()
+	defer h.cliMutex.Unlock()
+	err := h.thisHistory.append([]record.V2{*r})
+	if err != nil {
+		return fmt.Errorf("could not append record to history file: %w", err)
+	}
+	if h.cliLoaded {
+		h.cliRecords.AddRecord(r)
+	}
+	return nil
+}
+
+// AddCmdLines adds plain command lines (e.g. from bash/zsh history) to search data
+func (h *Histio) AddCmdLines(cmdLines []string) {
+	h.cliMutex.Lock()
+	defer h.cliMutex.Unlock()
+	for _, cmdLine := range cmdLines {
+		h.cliRecords.AddCmdLine(cmdLine)
+	}
+}
+
+// DumpCliRecords returns enriched records from all histories
+func (h *Histio) DumpCliRecords() histcli.Histcli {
+	h.cliMutex.RLock()
+	defer h.cliMutex.RUnlock()
+	list := make([]recordint.SearchApp, len(h.cliRecords.List))
+	copy(list, h.cliRecords.List)
+	return histcli.Histcli{List: list}
+}
+
+// CliRecords returns records for search without copying them
+// Returned slice must not be modified
+func (h *Histio) CliRecords() []recordint.SearchApp {
+	h.cliMutex.RLock()
+	defer h.cliMutex.RUnlock()
+	list := h.cliRecords.List
+	// full slice expression prevents appends from writing into the shared array
+	return list[:len(list):len(list)]
+}
+
+// SessionCliRecords returns records of the session sorted by time
+func (h *Histio) SessionCliRecords(sessionID string) []recordint.SearchApp {
+	var recs []recordint.SearchApp
+	for _, rec := range h.CliRecords() {
+		if rec.SessionID == sessionID && !rec.IsRaw {
+			recs = append(recs, rec)
+		}
+	}
+	sort.SliceStable(recs, func(i, j int) bool {
+		return recs[i].Time < recs[j].Time
+	})
+	return recs
+}
+
+func sortByTime(recs []record.V2) {
+	times := make(map[string]float64, len(recs))
+	for _, rec := range recs {
+		if _, found := times[rec.Time]; found {
+			continue
+		}
+		// invalid time is zero - such records end up first
+		tm, _ := strconv.ParseFloat(rec.Time, 64)
+		times[rec.Time] = tm
+	}
+	sort.SliceStable(recs, func(i, j int) bool {
+		return times[recs[i].Time] < times[recs[j].Time]
+	})
+}
+
+// Flag sets the flag on records with the command line and saves the history of this device
+// Histories of other devices are not ours to modify - their records are never flagged
+// Deleted command is hidden from search in histories of other devices but their files still contain it
+// Returns number of records whose flag changed and number of records of other devices that were hidden
+func (h *Histio) Flag(flag recordint.Flag) (flagged int, hidden int, err error) {
+	if !flag.IsValid() {
+		return 0, 0, fmt.Errorf("unknown flag: '%s'", flag.Name)
+	}
+	changed, err := h.thisHistory.flag(flag)
+	if err != nil {
+		return 0, 0, err
+	}
+	var others []record.V2
+	if flag.Name == recordint.FlagDeleted {
+		for _, hf := range h.otherHistories() {
+			for _, rec := range hf.records() {
+				if flag.Matches(rec.CmdLine) && !rec.Deleted {
+					others = append(others, rec)
+				}
+			}
+		}
+	}
+
+	h.cliMutex.Lock()
+	defer h.cliMutex.Unlock()
+	if flag.Name == recordint.FlagDeleted && !flag.Value {
+		if len(changed) == 0 {
+			return 0, 0, nil
+		}
+		// deleted records are not in search data - put them back
+		changed = append(changed, others...)
+		for i := range changed {
+			h.cliRecords.AddRecord(&changed[i])
+		}
+		return len(changed) - len(others), 0, nil
+	}
+	ids := make(map[string]bool, len(changed)+len(others))
+	for _, rec := range append(changed, others...) {
+		// records without ID can't be told apart
+		if rec.RecordID != "" {
+			ids[rec.RecordID] = true
+		}
+	}
+	h.cliRecords.Flag(flag, ids)
+	return len(changed), len(others), nil
 }
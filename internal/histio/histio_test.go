@@ -0,0 +1,314 @@
+package histio
+
+import (
+	"errors"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+
+	"github.com/curusarn/resh/internal/datadir"
+	"github.com/curusarn/resh/internal/recordint"
+	"github.com/curusarn/resh/record"
+	"go.uber.org/zap"
+)
+
+func TestLoadMergesDevices(t *testing.T) {
+	sugar := zap.NewNop().Sugar()
+	dataDir := t.TempDir()
+	histDir := path.Join(dataDir, datadir.HistoryDirName)
+	if err := os.MkdirAll(histDir, 0755); err != nil {
+		t.Fatalf("Test setup failed: %v", err)
+	}
+	other := `v1{"cmdLine":"ls","deviceID":"other","time":"2.0000"}` + "\n"
+	if err := os.WriteFile(path.Join(histDir, "other"), []byte(other), 0644); err != nil {
+		t.Fatalf("Test setup failed: %v", err)
+	}
+	// backups should be ignored
+	if err := os.WriteFile(path.Join(histDir, "other.bak"), []byte(other), 0644); err != nil {
+		t.Fatalf("Test setup failed: %v", err)
+	}
+
+	hio := New(sugar, dataDir, "this")
+	if err := hio.Load(); err != nil {
+		t.Fatalf("Unexpected error during load: %v", err)
+	}
+	for _, tm := range []string{"1.0000", "3.0000"} {
+		err := hio.Append(&record.V2{CmdLine: "pwd", DeviceID: "this", Time: tm})
+		if err != nil {
+			t.Fatalf("Unexpected error during append: %v", err)
+		}
+	}
+
+	recs := hio.Records()
+	if len(recs) != 3 {
+		t.Fatalf("Expected 3 records, got %d", len(recs))
+	}
+	if recs[0].Time != "1.0000" || recs[1].Time != "2.0000" || recs[2].Time != "3.0000" {
+		t.Fatalf("Records are not ordered by time: %v", recs)
+	}
+	if len(hio.DumpCliRecords().List) != 3 {
+		t.Fatalf("Expected 3 search records, got %d", len(hio.DumpCliRecords().List))
+	}
+
+	// records of this device are only written into its own file
+	reloaded := New(sugar, dataDir, "other")
+	if err := reloaded.Load(); err != nil {
+		t.Fatalf("Unexpected error during reload: %v", err)
+	}
+	if len(reloaded.thisHistory.records()) != 1 {
+		t.Fatalf("History of other device was modified")
+	}
+}
+
+func TestAppendDuringLoad(t *testing.T) {
+	sugar := zap.NewNop().Sugar()
+	dataDir := t.TempDir()
+	histDir := path.Join(dataDir, datadir.HistoryDirName)
+	if err := os.MkdirAll(histDir, 0755); err != nil {
+		t.Fatalf("Test setup failed: %v", err)
+	}
+	var history []byte
+	for i := 0; i < 1000; i++ {
+		history = append(history, `v1{"cmdLine":"ls","time":"1.0000"}`+"\n"...)
+	}
+	if err := os.WriteFile(path.Join(histDir, "this"), history, 0644); err != nil {
+		t.Fatalf("Test setup failed: %v", err)
+	}
+
+	hio := New(sugar, dataDir, "this")
+	loaded := make(chan error)
+	go func() {
+		loaded <- hio.Load()
+	}()
+	for i := 0; i < 100; i++ {
+		if err := hio.Append(&record.V2{CmdLine: "pwd", Time: "2.0000"}); err != nil {
+			t.Fatalf("Unexpected error during append: %v", err)
+		}
+	}
+	if err := <-loaded; err != nil {
+		t.Fatalf("Unexpected error during load: %v", err)
+	}
+	if len(hio.Records()) != 1100 {
+		t.Fatalf("Expected 1100 records, got %d", len(hio.Records()))
+	}
+	if len(hio.DumpCliRecords().List) != 1100 {
+		t.Fatalf("Expected 1100 search records, got %d", len(hio.DumpCliRecords().List))
+	}
+}
+
+func TestFlagIsSaved(t *testing.T) {
+	sugar := zap.NewNop().Sugar()
+	dataDir := t.TempDir()
+
+	hio := New(sugar, dataDir, "this")
+	if err := hio.Load(); err != nil {
+		t.Fatalf("Unexpected error during load: %v", err)
+	}
+	for _, id := range []string{"a", "b", "c"} {
+		err := hio.Append(&record.V2{CmdLine: "echo " + id, RecordID: id, Time: "1.0000"})
+		if err != nil {
+			t.Fatalf("Unexpected error during append: %v", err)
+		}
+	}
+	count, _, err := hio.Flag(recordint.Flag{CmdLine: "echo a", Name: recordint.FlagDeleted, Value: true})
+	if err != nil || count != 1 {
+		t.Fatalf("Expected 1 deleted record, got %d (error: %v)", count, err)
+	}
+	count, _, err = hio.Flag(recordint.Flag{CmdLine: "echo b", Name: recordint.FlagFavorite, Value: true})
+	if err != nil || count != 1 {
+		t.Fatalf("Expected 1 favorite record, got %d (error: %v)", count, err)
+	}
+	_, _, err = hio.Flag(recordint.Flag{CmdLine: "echo c", Name: "unknown", Value: true})
+	if err == nil {
+		t.Fatalf("Expected error for unknown flag")
+	}
+
+	reloaded := New(sugar, dataDir, "this")
+	if err := reloaded.Load(); err != nil {
+		t.Fatalf("Unexpected error during reload: %v", err)
+	}
+	recs := reloaded.Records()
+	if len(recs) != 3 || !recs[0].Deleted || !recs[1].Favorite {
+		t.Fatalf("Flags were not saved: %v", recs)
+	}
+	dump := reloaded.DumpCliRecords().List
+	if len(dump) != 2 {
+		t.Fatalf("Deleted record should not be dumped, got %d records", len(dump))
+	}
+	for _, rec := range dump {
+		if rec.RecordID == "b" && !rec.Favorite {
+			t.Fatalf("Favorite flag is missing in dumped record")
+		}
+	}
+}
+
+func TestFlagOnlyChangesThisDevice(t *testing.T) {
+	sugar := zap.NewNop().Sugar()
+	dataDir := t.TempDir()
+	histDir := path.Join(dataDir, datadir.HistoryDirName)
+	if err := os.MkdirAll(histDir, 0755); err != nil {
+		t.Fatalf("Test setup failed: %v", err)
+	}
+	other := `v1{"cmdLine":"echo a","recordID":"o","deviceID":"other","time":"2.0000"}` + "\n"
+	otherPath := path.Join(histDir, "other")
+	if err := os.WriteFile(otherPath, []byte(other), 0644); err != nil {
+		t.Fatalf("Test setup failed: %v", err)
+	}
+
+	hio := New(sugar, dataDir, "this")
+	if err := hio.Load(); err != nil {
+		t.Fatalf("Unexpected error during load: %v", err)
+	}
+	// duplicates of the command are all flagged
+	for _, id := range []string{"a1", "a2", "b"} {
+		err := hio.Append(&record.V2{CmdLine: "echo " + id[:1], RecordID: id, Time: "1.0000"})
+		if err != nil {
+			t.Fatalf("Unexpected error during append: %v", err)
+		}
+	}
+	count, hidden, err := hio.Flag(recordint.Flag{CmdLine: "echo a ", Name: recordint.FlagDeleted, Value: true})
+	if err != nil || count != 2 || hidden != 1 {
+		t.Fatalf("Expected 2 deleted and 1 hidden record, got %d and %d (error: %v)", count, hidden, err)
+	}
+	data, err := os.ReadFile(otherPath)
+	if err != nil || string(data) != other {
+		t.Fatalf("History of other device was modified: %s (error: %v)", data, err)
+	}
+	// deleted command is hidden in history of other device
+	if len(hio.DumpCliRecords().List) != 1 {
+		t.Fatalf("Expected 1 search record, got %d", len(hio.DumpCliRecords().List))
+	}
+	reloaded := New(sugar, dataDir, "this")
+	if err := reloaded.Load(); err != nil {
+		t.Fatalf("Unexpected error during reload: %v", err)
+	}
+	if len(reloaded.DumpCliRecords().List) != 1 {
+		t.Fatalf("Expected 1 search record after reload, got %d", len(reloaded.DumpCliRecords().List))
+	}
+
+	// only records that were deleted are put back
+	count, _, err = hio.Flag(recordint.Flag{CmdLine: "echo b", Name: recordint.FlagDeleted, Value: false})
+	if err != nil || count != 0 {
+		t.Fatalf("Expected no undeleted records, got %d (error: %v)", count, err)
+	}
+	count, _, err = hio.Flag(recordint.Flag{CmdLine: "echo a", Name: recordint.FlagDeleted, Value: false})
+	if err != nil || count != 2 {
+		t.Fatalf("Expected 2 undeleted records, got %d (error: %v)", count, err)
+	}
+	if len(hio.DumpCliRecords().List) != 4 {
+		t.Fatalf("Expected 4 search records, got %d", len(hio.DumpCliRecords().List))
+	}
+}
+
+func TestFlagKeepsInvalidLines(t *testing.T) {
+	sugar := zap.NewNop().Sugar()
+	dataDir := t.TempDir()
+
+	hio := New(sugar, dataDir, "this")
+	if err := hio.Load(); err != nil {
+		t.Fatalf("Unexpected error during load: %v", err)
+	}
+	if err := hio.Append(&record.V2{CmdLine: "echo a", RecordID: "a", Time: "1.0000"}); err != nil {
+		t.Fatalf("Unexpected error during append: %v", err)
+	}
+	histPath := GetPath(dataDir, "this")
+	file, err := os.OpenFile(histPath, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("Test setup failed: %v", err)
+	}
+	file.WriteString("not a record\n")
+	file.Close()
+	before, err := os.ReadFile(histPath)
+	if err != nil {
+		t.Fatalf("Test setup failed: %v", err)
+	}
+
+	if _, _, err := hio.Flag(recordint.Flag{CmdLine: "echo a", Name: recordint.FlagFavorite, Value: true}); err == nil {
+		t.Fatal("Expected error when history file contains invalid lines")
+	}
+	after, err := os.ReadFile(histPath)
+	if err != nil || string(after) != string(before) {
+		t.Fatalf("History file with invalid lines was rewritten: %s (error: %v)", after, err)
+	}
+}
+
+func TestIndexFollowsHistoryFile(t *testing.T) {
+	sugar := zap.NewNop().Sugar()
+	dataDir := t.TempDir()
+	histPath := GetPath(dataDir, "this")
+	indexPath := path.Join(dataDir, datadir.IndexDirName, "this")
+
+	hio := New(sugar, dataDir, "this")
+	if err := hio.Load(); err != nil {
+		t.Fatalf("Unexpected error during load: %v", err)
+	}
+	for _, id := range []string{"a", "b"} {
+		err := hio.Append(&record.V2{CmdLine: "echo " + id, RecordID: id, Time: "1.0000"})
+		if err != nil {
+			t.Fatalf("Unexpected error during append: %v", err)
+		}
+	}
+	info, err := os.Stat(histPath)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	recs, err := readIndex(indexPath, info)
+	if err != nil {
+		t.Fatalf("Expected index to be updated on append: %v", err)
+	}
+	if len(recs) != 2 || recs[1].RecordID != "b" {
+		t.Fatalf("Unexpected records in index: %v", recs)
+	}
+
+	// someone else appends to the history file
+	file, err := os.OpenFile(histPath, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("Test setup failed: %v", err)
+	}
+	file.WriteString(`v2{"cmdLine":"ls","recordID":"c","time":"2.0000"}` + "\n")
+	file.Close()
+	info, err = os.Stat(histPath)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := readIndex(indexPath, info); !errors.Is(err, errIndexStale) {
+		t.Fatalf("Expected stale index, got: %v", err)
+	}
+
+	reloaded := New(sugar, dataDir, "this")
+	if err := reloaded.Load(); err != nil {
+		t.Fatalf("Unexpected error during reload: %v", err)
+	}
+	if len(reloaded.Records()) != 3 {
+		t.Fatalf("Expected 3 records after reload, got %d", len(reloaded.Records()))
+	}
+	if recs, err := readIndex(indexPath, info); err != nil || len(recs) != 3 {
+		t.Fatalf("Expected index to be rebuilt on load, got %d records (error: %v)", len(recs), err)
+	}
+}
+
+func TestIndexRecordRoundTrip(t *testing.T) {
+	recs := []record.V2{
+		{CmdLine: "ls -la", ExitCode: -1, Deleted: true, GitDirty: true, Time: "1.5"},
+		{CmdLine: "echo '♥'\nmultiline", ExitCode: 300, Favorite: true, SessionExit: true, GitBranch: "main"},
+		{CmdLine: "kubectl get pods", Env: map[string]string{"AWS_PROFILE": "prod", "KUBECONFIG": "/home/user/.kube/prod"}},
+		{},
+	}
+	decoded, err := decodeIndexRecords(string(encodeIndexRecords(recs)), len(recs))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(decoded) != len(recs) {
+		t.Fatalf("Expected %d records, got %d", len(recs), len(decoded))
+	}
+	for i := range recs {
+		if !reflect.DeepEqual(decoded[i], recs[i]) {
+			t.Fatalf("Record %d changed during round trip: %v != %v", i, decoded[i], recs[i])
+		}
+	}
+	truncated := encodeIndexRecords(recs[:1])
+	if _, err := decodeIndexRecords(string(truncated[:len(truncated)-1]), 1); err == nil {
+		t.Fatal("Expected error for truncated index")
+	}
+}
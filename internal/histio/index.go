@@ -0,0 +1,336 @@
+package histio
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/curusarn/resh/record"
+)
+
+// Index is a snapshot of decoded records of one history file
+// Loading it is much faster than decoding JSON of the whole history file
+//
+// Index file starts with a fixed size header followed by binary-encoded records:
+//
+//	header: magic (8B) | history file size (8B) | history file mtime in ns (8B) | record count (8B)
+//	record: see encodeIndexRecord
+//
+// Records appended to history file are appended to the index.
+// Index is only valid when size and mtime in the header match the history file.
+
+// NOTE: change the magic whenever the record encoding changes - old indexes get rebuilt
+const indexMagic = "RESHIDX2"
+
+const indexHeaderSize = len(indexMagic) + 8 + 8 + 8
+
+// errIndexStale is returned when the index doesn't match the history file
+var errIndexStale = errors.New("index does not match history file")
+
+type indexHeader struct {
+	size    int64
+	modTime int64
+	// number of records allows allocating all of them at once
+	count int64
+}
+
+func newIndexHeader(info os.FileInfo, count int) indexHeader {
+	return indexHeader{
+		size:    info.Size(),
+		modTime: info.ModTime().UnixNano(),
+		count:   int64(count),
+	}
+}
+
+// matches returns true if the index belongs to the history file described by info
+func (h indexHeader) matches(info os.FileInfo) bool {
+	return h.size == info.Size() && h.modTime == info.ModTime().UnixNano()
+}
+
+func (h indexHeader) encode() []byte {
+	buf := make([]byte, indexHeaderSize)
+	copy(buf, indexMagic)
+	binary.LittleEndian.PutUint64(buf[len(indexMagic):], uint64(h.size))
+	binary.LittleEndian.PutUint64(buf[len(indexMagic)+8:], uint64(h.modTime))
+	binary.LittleEndian.PutUint64(buf[len(indexMagic)+16:], uint64(h.count))
+	return buf
+}
+
+func readIndexHeader(r io.Reader) (indexHeader, error) {
+	buf := make([]byte, indexHeaderSize)
+	_, err := io.ReadFull(r, buf)
+	if err != nil {
+		return indexHeader{}, fmt.Errorf("could not read index header: %w", err)
+	}
+	if string(buf[:len(indexMagic)]) != indexMagic {
+		return indexHeader{}, fmt.Errorf("unknown index format")
+	}
+	return indexHeader{
+		size:    int64(binary.LittleEndian.Uint64(buf[len(indexMagic):])),
+		modTime: int64(binary.LittleEndian.Uint64(buf[len(indexMagic)+8:])),
+		count:   int64(binary.LittleEndian.Uint64(buf[len(indexMagic)+16:])),
+	}, nil
+}
+
+// bool fields of the record are stored as bits of one byte
+const (
+	indexFlagDeleted = 1 << iota
+	indexFlagFavorite
+	indexFlagGitDirty
+	indexFlagPartOne
+	indexFlagPartsNotMerged
+	indexFlagSessionExit
+)
+
+// indexStrings returns pointers to all string fields of the record in the order they are stored
+func indexStrings(rec *record.V2) []*string {
+	return []*string{
+		&rec.CmdLine,
+		&rec.DeviceID,
+		&rec.SessionID,
+		&rec.RecordID,
+		&rec.Home,
+		&rec.Pwd,
+		&rec.RealPwd,
+		&rec.Device,
+		&rec.GitOriginRemote,
+		&rec.GitBranch,
+		&rec.GitCommit,
+		&rec.GitToplevel,
+		&rec.Time,
+		&rec.Duration,
+	}
+}
+
+// encodeIndexRecord appends the record to buf
+// record: flags (1B) | exit code (varint) | string fields (uvarint length + bytes each) |
+// env count (uvarint) | env keys and values (stored as string fields)
+func encodeIndexRecord(buf []byte, rec record.V2) []byte {
+	var flags byte
+	setFlag := func(flag byte, set bool) {
+		if set {
+			flags |= flag
+		}
+	}
+	setFlag(indexFlagDeleted, rec.Deleted)
+	setFlag(indexFlagFavorite, rec.Favorite)
+	setFlag(indexFlagGitDirty, rec.GitDirty)
+	setFlag(indexFlagPartOne, rec.PartOne)
+	setFlag(indexFlagPartsNotMerged, rec.PartsNotMerged)
+	setFlag(indexFlagSessionExit, rec.SessionExit)
+	buf = append(buf, flags)
+	buf = binary.AppendVarint(buf, int64(rec.ExitCode))
+	appendString := func(str string) {
+		buf = binary.AppendUvarint(buf, uint64(len(str)))
+		buf = append(buf, str...)
+	}
+	for _, str := range indexStrings(&rec) {
+		appendString(*str)
+	}
+	buf = binary.AppendUvarint(buf, uint64(len(rec.Env)))
+	for key, value := range rec.Env {
+		appendString(key)
+		appendString(value)
+	}
+	return buf
+}
+
+// decodeIndexRecords decodes all records from data
+// Strings of decoded records share memory with data to avoid allocating each of them
+func decodeIndexRecords(data string, count int) ([]record.V2, error) {
+	// don't trust the count blindly - each record takes at least 16 bytes
+	if maxCount := len(data) / 16; count > maxCount {
+		count = maxCount
+	}
+	recs := make([]record.V2, 0, count)
+	errTruncated := fmt.Errorf("truncated index record")
+	for len(data) > 0 {
+		recs = append(recs, record.V2{})
+		rec := &recs[len(recs)-1]
+		flags := data[0]
+		data = data[1:]
+		rec.Deleted = flags&indexFlagDeleted != 0
+		rec.Favorite = flags&indexFlagFavorite != 0
+		rec.GitDirty = flags&indexFlagGitDirty != 0
+		rec.PartOne = flags&indexFlagPartOne != 0
+		rec.PartsNotMerged = flags&indexFlagPartsNotMerged != 0
+		rec.SessionExit = flags&indexFlagSessionExit != 0
+
+		ux, n := uvarint(data)
+		if n <= 0 {
+			return nil, errTruncated
+		}
+		// zigzag decoding - see binary.Varint
+		exitCode := int64(ux >> 1)
+		if ux&1 != 0 {
+			exitCode = ^exitCode
+		}
+		rec.ExitCode = int(exitCode)
+		data = data[n:]
+		readString := func() (string, bool) {
+			length, n := uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < length {
+				return "", false
+			}
+			str := data[n : n+int(length)]
+			data = data[n+int(length):]
+			return str, true
+		}
+		var ok bool
+		for _, str := range indexStrings(rec) {
+			if *str, ok = readString(); !ok {
+				return nil, errTruncated
+			}
+		}
+		envCount, n := uvarint(data)
+		// each variable takes at least 2 bytes
+		if n <= 0 || uint64(len(data)-n) < 2*envCount {
+			return nil, errTruncated
+		}
+		data = data[n:]
+		if envCount > 0 {
+			rec.Env = make(map[string]string, envCount)
+		}
+		for i := uint64(0); i < envCount; i++ {
+			key, okKey := readString()
+			value, okValue := readString()
+			if !okKey || !okValue {
+				return nil, errTruncated
+			}
+			rec.Env[key] = value
+		}
+	}
+	return recs, nil
+}
+
+// uvarint is binary.Uvarint for strings
+func uvarint(s string) (uint64, int) {
+	var x uint64
+	var shift uint
+	for i := 0; i < len(s) && i < binary.MaxVarintLen64; i++ {
+		b := s[i]
+		if b < 0x80 {
+			return x | uint64(b)<<shift, i + 1
+		}
+		x |= uint64(b&0x7f) << shift
+		shift += 7
+	}
+	return 0, 0
+}
+
+func encodeIndexRecords(recs []record.V2) []byte {
+	var buf []byte
+	for _, rec := range recs {
+		buf = encodeIndexRecord(buf, rec)
+	}
+	return buf
+}
+
+// readIndex returns records from index at ipath
+// Returns errIndexStale if the index doesn't match the history file described by info
+func readIndex(ipath string, info os.FileInfo) ([]record.V2, error) {
+	file, err := os.Open(ipath)
+	if err != nil {
+		return nil, fmt.Errorf("could not open index: %w", err)
+	}
+	defer file.Close()
+
+	header, err := readIndexHeader(file)
+	if err != nil {
+		return nil, err
+	}
+	if !header.matches(info) {
+		return nil, errIndexStale
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("could not stat index: %w", err)
+	}
+	// read into one string that is shared by all decoded records
+	var data strings.Builder
+	data.Grow(int(stat.Size()) - indexHeaderSize)
+	_, err = io.Copy(&data, file)
+	if err != nil {
+		return nil, fmt.Errorf("could not read index: %w", err)
+	}
+	recs, err := decodeIndexRecords(data.String(), int(header.count))
+	if err != nil {
+		return nil, err
+	}
+	if len(recs) != int(header.count) {
+		return nil, fmt.Errorf("index contains %d records, expected %d", len(recs), header.count)
+	}
+	return recs, nil
+}
+
+// writeIndex replaces index at ipath with records of history file described by info
+func writeIndex(ipath string, info os.FileInfo, recs []record.V2) error {
+	data := encodeIndexRecords(recs)
+	err := os.MkdirAll(path.Dir(ipath), 0755)
+	if err != nil {
+		return fmt.Errorf("could not create index directory: %w", err)
+	}
+	// write to temporary file and rename it so that readers never see partial index
+	tmpPath := ipath + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("could not create index: %w", err)
+	}
+	_, err = file.Write(newIndexHeader(info, len(recs)).encode())
+	if err == nil {
+		_, err = file.Write(data)
+	}
+	if err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("could not write index: %w", err)
+	}
+	err = file.Close()
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("could not close index: %w", err)
+	}
+	err = os.Rename(tmpPath, ipath)
+	if err != nil {
+		return fmt.Errorf("could not replace index: %w", err)
+	}
+	return nil
+}
+
+// appendToIndex adds records appended to history file to index at ipath
+// prevInfo describes history file before the records were appended and info after
+// Returns errIndexStale if the index doesn't match prevInfo
+func appendToIndex(ipath string, prevInfo, info os.FileInfo, recs []record.V2) error {
+	file, err := os.OpenFile(ipath, os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Errorf("could not open index: %w", err)
+	}
+	defer file.Close()
+
+	header, err := readIndexHeader(file)
+	if err != nil {
+		return err
+	}
+	if !header.matches(prevInfo) {
+		return errIndexStale
+	}
+	data := encodeIndexRecords(recs)
+	_, err = file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return fmt.Errorf("could not seek in index: %w", err)
+	}
+	_, err = file.Write(data)
+	if err != nil {
+		return fmt.Errorf("could not write index records: %w", err)
+	}
+	// header is updated last - index stays stale when anything above fails
+	_, err = file.WriteAt(newIndexHeader(info, int(header.count)+len(recs)).encode(), 0)
+	if err != nil {
+		return fmt.Errorf("could not write index header: %w", err)
+	}
+	return nil
+}
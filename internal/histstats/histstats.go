@@ -0,0 +1,272 @@
+// Package histstats computes statistics of recorded history
+package histstats
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/curusarn/resh/record"
+)
+
+// minRunsForFailureRate filters out commands that ran too few times for failure rate to be meaningful
+const minRunsForFailureRate = 3
+
+// Stats of recorded history
+type Stats struct {
+	RecordCount int       `json:"recordCount"`
+	First       time.Time `json:"first"`
+	Last        time.Time `json:"last"`
+
+	// most used command lines and executables
+	Commands    []Command `json:"commands"`
+	Executables []Command `json:"executables"`
+	// executables with the highest failure rate
+	Failing []Command `json:"failing"`
+	// longest running commands
+	Slowest []Slow `json:"slowest"`
+
+	// number of commands per hour of the day (local time)
+	Hours [24]int `json:"hours"`
+	// number of commands per day of the week - Sunday is 0 (see time.Weekday)
+	Weekdays [7]int `json:"weekdays"`
+
+	Directories []Count  `json:"directories"`
+	GitRepos    []Count  `json:"gitRepos"`
+	Devices     []Device `json:"devices"`
+}
+
+// Count of records with given name
+type Count struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// Command is a command line or an executable
+type Command struct {
+	Name        string  `json:"name"`
+	Count       int     `json:"count"`
+	Failed      int     `json:"failed"`
+	FailureRate float64 `json:"failureRate"`
+	// average duration in seconds of commands that have finished
+	AvgDuration float64 `json:"avgDuration"`
+
+	durationSum   float64
+	durationCount int
+}
+
+// Slow is one long running command
+type Slow struct {
+	CmdLine  string    `json:"cmdLine"`
+	Duration float64   `json:"duration"`
+	ExitCode int       `json:"exitCode"`
+	Time     time.Time `json:"time"`
+	Device   string    `json:"device"`
+}
+
+// Device stats
+type Device struct {
+	Name        string    `json:"name"`
+	Count       int       `json:"count"`
+	Failed      int       `json:"failed"`
+	First       time.Time `json:"first"`
+	Last        time.Time `json:"last"`
+	Directories int       `json:"directories"`
+}
+
+// Executable returns the program of the command line - leading variable assignments (e.g. FOO=bar) are skipped
+func Executable(cmdLine string) string {
+	for _, word := range strings.Fields(cmdLine) {
+		eq := strings.IndexByte(word, '=')
+		if eq > 0 && !strings.ContainsAny(word[:eq], "/-.'\"$") {
+			continue
+		}
+		return word
+	}
+	return ""
+}
+
+func parseTime(str string) (time.Time, bool) {
+	t, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	secs := int64(t)
+	return time.Unix(secs, int64((t-float64(secs))*1e9)), true
+}
+
+func (c *Command) add(rec *record.V2, duration float64, hasDuration bool) {
+	c.Count++
+	if rec.ExitCode != 0 {
+		c.Failed++
+	}
+	if hasDuration {
+		c.durationSum += duration
+		c.durationCount++
+	}
+}
+
+func (c *Command) finish() {
+	c.FailureRate = float64(c.Failed) / float64(c.Count)
+	if c.durationCount > 0 {
+		c.AvgDuration = c.durationSum / float64(c.durationCount)
+	}
+}
+
+// New computes stats of records - lists are limited to top items
+// Records without valid time are skipped
+func New(recs []record.V2, top int) Stats {
+	var stats Stats
+	commands := map[string]*Command{}
+	executables := map[string]*Command{}
+	dirs := map[string]int{}
+	repos := map[string]int{}
+	devices := map[string]*Device{}
+	deviceDirs := map[string]map[string]bool{}
+	var slowest []Slow
+
+	for i := range recs {
+		rec := &recs[i]
+		tm, ok := parseTime(rec.Time)
+		if !ok || rec.CmdLine == "" {
+			continue
+		}
+		stats.RecordCount++
+		if stats.First.IsZero() || tm.Before(stats.First) {
+			stats.First = tm
+		}
+		if tm.After(stats.Last) {
+			stats.Last = tm
+		}
+		duration, err := strconv.ParseFloat(rec.Duration, 64)
+		hasDuration := err == nil
+
+		cmdLine := strings.TrimSpace(rec.CmdLine)
+		if commands[cmdLine] == nil {
+			commands[cmdLine] = &Command{Name: cmdLine}
+		}
+		commands[cmdLine].add(rec, duration, hasDuration)
+		if exe := Executable(cmdLine); exe != "" {
+			if executables[exe] == nil {
+				executables[exe] = &Command{Name: exe}
+			}
+			executables[exe].add(rec, duration, hasDuration)
+		}
+		if hasDuration {
+			slowest = append(slowest, Slow{
+				CmdLine:  cmdLine,
+				Duration: duration,
+				ExitCode: rec.ExitCode,
+				Time:     tm,
+				Device:   rec.Device,
+			})
+		}
+
+		local := tm.Local()
+		stats.Hours[local.Hour()]++
+		stats.Weekdays[local.Weekday()]++
+
+		if rec.Pwd != "" {
+			dirs[rec.Pwd]++
+		}
+		if rec.GitOriginRemote != "" {
+			repos[rec.GitOriginRemote]++
+		}
+
+		dev := devices[rec.Device]
+		if dev == nil {
+			dev = &Device{Name: rec.Device, First: tm, Last: tm}
+			devices[rec.Device] = dev
+			deviceDirs[rec.Device] = map[string]bool{}
+		}
+		dev.Count++
+		if rec.ExitCode != 0 {
+			dev.Failed++
+		}
+		if tm.Before(dev.First) {
+			dev.First = tm
+		}
+		if tm.After(dev.Last) {
+			dev.Last = tm
+		}
+		deviceDirs[rec.Device][rec.Pwd] = true
+	}
+
+	stats.Commands = topCommands(commands, top, func(c *Command) bool { return true }, byCount)
+	stats.Executables = topCommands(executables, top, func(c *Command) bool { return true }, byCount)
+	stats.Failing = topCommands(executables, top, func(c *Command) bool {
+		return c.Failed > 0 && c.Count >= minRunsForFailureRate
+	}, byFailureRate)
+
+	sort.SliceStable(slowest, func(i, j int) bool { return slowest[i].Duration > slowest[j].Duration })
+	stats.Slowest = limit(slowest, top)
+
+	stats.Directories = topCounts(dirs, top)
+	stats.GitRepos = topCounts(repos, top)
+
+	for name, dev := range devices {
+		dev.Directories = len(deviceDirs[name])
+		stats.Devices = append(stats.Devices, *dev)
+	}
+	sort.Slice(stats.Devices, func(i, j int) bool {
+		if stats.Devices[i].Count != stats.Devices[j].Count {
+			return stats.Devices[i].Count > stats.Devices[j].Count
+		}
+		return stats.Devices[i].Name < stats.Devices[j].Name
+	})
+	return stats
+}
+
+func byCount(a, b *Command) bool {
+	if a.Count != b.Count {
+		return a.Count > b.Count
+	}
+	return a.Name < b.Name
+}
+
+func byFailureRate(a, b *Command) bool {
+	if a.FailureRate != b.FailureRate {
+		return a.FailureRate > b.FailureRate
+	}
+	return byCount(a, b)
+}
+
+func topCommands(commands map[string]*Command, top int, keep func(*Command) bool, less func(a, b *Command) bool) []Command {
+	var list []*Command
+	for _, c := range commands {
+		c.finish()
+		if keep(c) {
+			list = append(list, c)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return less(list[i], list[j]) })
+	list = limit(list, top)
+	result := make([]Command, 0, len(list))
+	for _, c := range list {
+		result = append(result, *c)
+	}
+	return result
+}
+
+func topCounts(counts map[string]int, top int) []Count {
+	list := make([]Count, 0, len(counts))
+	for name, count := range counts {
+		list = append(list, Count{Name: name, Count: count})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Count != list[j].Count {
+			return list[i].Count > list[j].Count
+		}
+		return list[i].Name < list[j].Name
+	})
+	return limit(list, top)
+}
+
+// limit returns at most top items - no limit when top is zero or negative
+func limit[T any](list []T, top int) []T {
+	if top > 0 && len(list) > top {
+		return list[:top]
+	}
+	return list
+}
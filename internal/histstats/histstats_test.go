@@ -0,0 +1,107 @@
+package histstats
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/curusarn/resh/record"
+)
+
+func rec(cmdLine string, tm time.Time, exitCode int, duration string, device, pwd, git string) record.V2 {
+	return record.V2{
+		CmdLine:         cmdLine,
+		Time:            strconv.FormatInt(tm.Unix(), 10),
+		ExitCode:        exitCode,
+		Duration:        duration,
+		Device:          device,
+		Pwd:             pwd,
+		GitOriginRemote: git,
+	}
+}
+
+func TestExecutable(t *testing.T) {
+	data := map[string]string{
+		"git status":             "git",
+		"  ls -la ":              "ls",
+		"FOO=bar BAZ=1 make all": "make",
+		"./build.sh --opt=1":     "./build.sh",
+		"--opt=1":                "--opt=1",
+		"":                       "",
+	}
+	for cmdLine, expected := range data {
+		if exe := Executable(cmdLine); exe != expected {
+			t.Fatalf("Incorrect executable for '%s': expected '%s', got '%s'", cmdLine, expected, exe)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	// Monday 9:00 and Wednesday 15:00 local time
+	mon := time.Date(2024, 1, 29, 9, 0, 0, 0, time.Local)
+	wed := time.Date(2024, 1, 31, 15, 0, 0, 0, time.Local)
+	recs := []record.V2{
+		rec("make", mon, 0, "10", "laptop", "/resh", "git@github.com:curusarn/resh.git"),
+		rec("make", mon, 2, "20", "laptop", "/resh", "git@github.com:curusarn/resh.git"),
+		rec("make test", wed, 1, "120", "laptop", "/resh", "git@github.com:curusarn/resh.git"),
+		rec("ls", wed, 0, "0.01", "server", "/home", ""),
+		rec("ls", wed, 0, "", "server", "/tmp", ""),
+		rec("ls", wed, 0, "0.02", "server", "/home", ""),
+		{CmdLine: "invalid time", Time: "x"},
+	}
+	stats := New(recs, 2)
+
+	if stats.RecordCount != 6 {
+		t.Fatalf("Expected 6 records, got %d", stats.RecordCount)
+	}
+	if !stats.First.Equal(mon) || !stats.Last.Equal(wed) {
+		t.Fatalf("Incorrect time span: %v - %v", stats.First, stats.Last)
+	}
+	if len(stats.Commands) != 2 || stats.Commands[0].Name != "ls" || stats.Commands[1].Name != "make" {
+		t.Fatalf("Incorrect most used commands: %+v", stats.Commands)
+	}
+	mk := stats.Executables[1]
+	if mk.Name != "make" || mk.Count != 3 || mk.Failed != 2 || mk.AvgDuration != 50 {
+		t.Fatalf("Incorrect stats of 'make': %+v", mk)
+	}
+	if len(stats.Failing) != 1 || stats.Failing[0].Name != "make" {
+		t.Fatalf("Incorrect failing executables: %+v", stats.Failing)
+	}
+	if len(stats.Slowest) != 2 || stats.Slowest[0].CmdLine != "make test" || stats.Slowest[1].Duration != 20 {
+		t.Fatalf("Incorrect slowest commands: %+v", stats.Slowest)
+	}
+	if stats.Hours[9] != 2 || stats.Hours[15] != 4 {
+		t.Fatalf("Incorrect activity by hour: %v", stats.Hours)
+	}
+	if stats.Weekdays[time.Monday] != 2 || stats.Weekdays[time.Wednesday] != 4 {
+		t.Fatalf("Incorrect activity by weekday: %v", stats.Weekdays)
+	}
+	if len(stats.Directories) != 2 || stats.Directories[0].Name != "/resh" || stats.Directories[1].Name != "/home" {
+		t.Fatalf("Incorrect top directories: %+v", stats.Directories)
+	}
+	if len(stats.GitRepos) != 1 || stats.GitRepos[0].Count != 3 {
+		t.Fatalf("Incorrect top git repos: %+v", stats.GitRepos)
+	}
+	if len(stats.Devices) != 2 {
+		t.Fatalf("Expected 2 devices, got %+v", stats.Devices)
+	}
+	laptop := stats.Devices[0]
+	if laptop.Name != "laptop" || laptop.Count != 3 || laptop.Failed != 2 || laptop.Directories != 1 {
+		t.Fatalf("Incorrect stats of device 'laptop': %+v", laptop)
+	}
+	server := stats.Devices[1]
+	if server.Directories != 2 || !server.First.Equal(wed) {
+		t.Fatalf("Incorrect stats of device 'server': %+v", server)
+	}
+}
+
+func TestNewNoLimit(t *testing.T) {
+	now := time.Now()
+	var recs []record.V2
+	for i := 0; i < 5; i++ {
+		recs = append(recs, rec("cmd"+strconv.Itoa(i), now, 0, "1", "d", "/", ""))
+	}
+	if stats := New(recs, 0); len(stats.Commands) != 5 || len(stats.Slowest) != 5 {
+		t.Fatalf("Expected all items without limit: %+v", stats)
+	}
+}
@@ -1,12 +1,43 @@
+// Package httpclient implements clients for communication with RESH daemon
 package httpclient
 
 import (
+	"context"
+	"fmt"
+	"net"
 	"net/http"
+	"strconv"
 	"time"
+
+	"github.com/curusarn/resh/internal/cfg"
+	"github.com/curusarn/resh/internal/datadir"
 )
 
-func New() *http.Client {
+// New client for RESH daemon
+// Unix socket is used unless TCP is enabled in config
+func New(config cfg.Config, timeout time.Duration) (*http.Client, error) {
+	if config.UseTCP {
+		return &http.Client{Timeout: timeout}, nil
+	}
+	socketPath, err := datadir.GetSocketPath()
+	if err != nil {
+		return nil, fmt.Errorf("could not get daemon socket path: %w", err)
+	}
+	dial := func(ctx context.Context, _, _ string) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, "unix", socketPath)
+	}
 	return &http.Client{
-		Timeout: 500 * time.Millisecond,
+		Timeout:   timeout,
+		Transport: &http.Transport{DialContext: dial},
+	}, nil
+}
+
+// URL of daemon endpoint
+func URL(config cfg.Config, path string) string {
+	if config.UseTCP {
+		return "http://localhost:" + strconv.Itoa(config.Port) + path
 	}
+	// host is ignored - client always dials the socket
+	return "http://resh" + path
 }
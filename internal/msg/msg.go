@@ -1,6 +1,10 @@
 package msg
 
-import "github.com/curusarn/resh/internal/recordint"
+import (
+	"github.com/curusarn/resh/internal/bus"
+	"github.com/curusarn/resh/internal/recordint"
+	"github.com/curusarn/resh/internal/searchapp"
+)
 
 // CliMsg struct
 type CliMsg struct {
@@ -13,9 +17,48 @@ type CliResponse struct {
 	Records []recordint.SearchApp
 }
 
+// SearchMsg is a query for daemon-side search
+// Daemon responds with a stream of JSON encoded searchapp.Result - one per line
+type SearchMsg struct {
+	SessionID string
+	Query     string
+	// Raw search ignores context
+	Raw bool
+	// Fuzzy search matches query terms as subsequences
+	Fuzzy bool
+
+	// context used for ranking
+	PWD             string
+	GitOriginRemote string
+	GitBranch       string
+	// values of CaptureEnv environment variables
+	Env map[string]string
+
+	Options searchapp.Options
+}
+
+// SessionMsg asks daemon for all records of a session
+type SessionMsg struct {
+	SessionID string
+}
+
+// SessionResponse holds records of the session sorted by time
+type SessionResponse struct {
+	Records []recordint.SearchApp
+}
+
+// FlagResponse struct
+type FlagResponse struct {
+	FlaggedCount int
+	// records of other devices that were hidden from search - their history files are not modified
+	HiddenCount int
+}
+
 // StatusResponse struct
 type StatusResponse struct {
 	Status  bool   `json:"status"`
 	Version string `json:"version"`
 	Commit  string `json:"commit"`
+	// internal queues of the daemon
+	Queues []bus.Stats `json:"queues,omitempty"`
 }
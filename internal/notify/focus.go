@@ -0,0 +1,38 @@
+package notify
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/curusarn/resh/internal/recordint"
+)
+
+// SystemFocus checks focus of tmux panes and X11 windows
+// Terminals where focus can't be detected (e.g. Wayland, macOS) are never focused
+// Active tmux pane is focused when the window of the terminal is unknown
+type SystemFocus struct{}
+
+// IsFocused returns true if the tmux pane is active and the terminal window is focused
+func (SystemFocus) IsFocused(term recordint.Terminal) bool {
+	inTmux := term.Tmux != "" && term.TmuxPane != ""
+	if inTmux {
+		// $TMUX is "socket,pid,session"
+		socket := strings.Split(term.Tmux, ",")[0]
+		out, err := exec.Command("tmux", "-S", socket, "display-message", "-p", "-t", term.TmuxPane,
+			"#{pane_active}#{window_active}#{?session_attached,1,0}").Output()
+		if err != nil || strings.TrimSpace(string(out)) != "111" {
+			return false
+		}
+	}
+	if term.WindowID == "" || term.Display == "" {
+		return inTmux
+	}
+	cmd := exec.Command("xdotool", "getactivewindow")
+	cmd.Env = append(os.Environ(), "DISPLAY="+term.Display)
+	out, err := cmd.Output()
+	if err != nil {
+		return false
+	}
+	return strings.TrimSpace(string(out)) == term.WindowID
+}
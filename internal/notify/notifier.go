@@ -0,0 +1,78 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/curusarn/resh/record"
+)
+
+// notifiers are killed when they run longer than this
+const notifierTimeout = 30 * time.Second
+
+// Command runs an executable with the record as JSON on stdin
+type Command struct {
+	Path string
+}
+
+// Notify runs the command
+func (c Command) Notify(rec record.V2) error {
+	jsn, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("could not marshal record: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), notifierTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, c.Path)
+	cmd.Stdin = bytes.NewReader(jsn)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("notify command '%s' failed: %w - output: %s", c.Path, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// Desktop shows desktop notification using notify-send on Linux or osascript on macOS
+type Desktop struct{}
+
+// Notify shows the notification
+func (d Desktop) Notify(rec record.V2) error {
+	title, body := message(rec)
+	var cmd *exec.Cmd
+	if runtime.GOOS == "darwin" {
+		script := fmt.Sprintf("display notification %s with title %s", appleScriptString(body), appleScriptString(title))
+		cmd = exec.Command("osascript", "-e", script)
+	} else {
+		cmd = exec.Command("notify-send", "--app-name=RESH", title, body)
+	}
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("could not show desktop notification: %w - output: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// message returns title and body of the notification
+func message(rec record.V2) (string, string) {
+	status := "finished"
+	if rec.ExitCode != 0 {
+		status = fmt.Sprintf("failed (exit code %d)", rec.ExitCode)
+	}
+	duration, err := strconv.ParseFloat(rec.Duration, 64)
+	if err == nil {
+		status += " after " + time.Duration(duration*float64(time.Second)).Round(time.Second).String()
+	}
+	return "Command " + status, rec.CmdLine
+}
+
+func appleScriptString(str string) string {
+	str = strings.ReplaceAll(str, `\`, `\\`)
+	return `"` + strings.ReplaceAll(str, `"`, `\"`) + `"`
+}
@@ -0,0 +1,126 @@
+// Package notify sends notifications when long running commands finish
+package notify
+
+import (
+	"strconv"
+
+	"github.com/curusarn/resh/internal/cfg"
+	"github.com/curusarn/resh/internal/recordint"
+	"github.com/curusarn/resh/internal/recutil"
+	"github.com/curusarn/resh/internal/redact"
+	"github.com/curusarn/resh/record"
+	"go.uber.org/zap"
+)
+
+// Notifier notifies the user about a finished command
+type Notifier interface {
+	Notify(rec record.V2) error
+}
+
+// FocusChecker tells if the terminal is focused
+type FocusChecker interface {
+	IsFocused(term recordint.Terminal) bool
+}
+
+type watcher struct {
+	sugar    *zap.SugaredLogger
+	config   cfg.Notify
+	notifier Notifier
+	focus    FocusChecker
+	redactor *redact.Redactor
+
+	// first parts of records waiting for the second part
+	sessions map[string]recordint.Collect
+}
+
+// New returns notifier based on the config - command when it's set, desktop notification otherwise
+func New(config cfg.Notify) Notifier {
+	if config.Command != "" {
+		return Command{Path: config.Command}
+	}
+	return Desktop{}
+}
+
+// Go runs the watcher - it merges records and notifies about the long running ones
+func Go(sugar *zap.SugaredLogger, config cfg.Notify, records <-chan recordint.Collect, sessionsToDrop <-chan string,
+	notifier Notifier, focus FocusChecker, redactor *redact.Redactor) {
+
+	w := newWatcher(sugar, config, notifier, focus, redactor)
+	go w.run(records, sessionsToDrop)
+}
+
+func newWatcher(sugar *zap.SugaredLogger, config cfg.Notify, notifier Notifier, focus FocusChecker, redactor *redact.Redactor) *watcher {
+	return &watcher{
+		sugar:    sugar.With("module", "notify"),
+		config:   config,
+		notifier: notifier,
+		focus:    focus,
+		redactor: redactor,
+		sessions: map[string]recordint.Collect{},
+	}
+}
+
+func (w *watcher) run(records <-chan recordint.Collect, sessionsToDrop <-chan string) {
+	for {
+		select {
+		case rec := <-records:
+			if merged, term, ok := w.handle(rec); ok {
+				// checking focus and notifying runs external programs
+				go w.notify(merged, term)
+			}
+		case sessionID := <-sessionsToDrop:
+			for mergeID, part1 := range w.sessions {
+				if part1.SessionID == sessionID {
+					delete(w.sessions, mergeID)
+				}
+			}
+		}
+	}
+}
+
+// handle returns merged record when it's complete and ran long enough
+func (w *watcher) handle(rec recordint.Collect) (record.V2, recordint.Terminal, bool) {
+	// allows nested sessions to merge records properly
+	mergeID := rec.SessionID + "_" + strconv.Itoa(rec.Shlvl)
+	if rec.Rec.PartOne {
+		w.sessions[mergeID] = rec
+		return record.V2{}, recordint.Terminal{}, false
+	}
+	part1, found := w.sessions[mergeID]
+	if !found {
+		return record.V2{}, recordint.Terminal{}, false
+	}
+	delete(w.sessions, mergeID)
+
+	duration, err := strconv.ParseFloat(rec.Rec.Duration, 64)
+	if err != nil || duration < w.config.MinDurationSeconds {
+		return record.V2{}, recordint.Terminal{}, false
+	}
+	merged, err := recutil.Merge(&part1, &rec)
+	if err != nil {
+		w.sugar.Errorw("Error while merging records", "error", err)
+		return record.V2{}, recordint.Terminal{}, false
+	}
+	// notifications shouldn't show secrets that never make it to history
+	cmdLine, keep := w.redactor.CmdLine(merged.CmdLine)
+	if !keep {
+		return record.V2{}, recordint.Terminal{}, false
+	}
+	merged.CmdLine = cmdLine
+	return merged, part1.Terminal, true
+}
+
+func (w *watcher) notify(rec record.V2, term recordint.Terminal) {
+	sugar := w.sugar.With(
+		"cmdLine", rec.CmdLine,
+		"duration", rec.Duration,
+	)
+	if w.config.OnlyUnfocused && w.focus.IsFocused(term) {
+		sugar.Debugw("Terminal is focused - not notifying")
+		return
+	}
+	sugar.Debugw("Notifying about long running command ...")
+	if err := w.notifier.Notify(rec); err != nil {
+		sugar.Errorw("Error while notifying", "error", err)
+	}
+}
@@ -0,0 +1,145 @@
+package notify
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/curusarn/resh/internal/cfg"
+	"github.com/curusarn/resh/internal/recordint"
+	"github.com/curusarn/resh/internal/redact"
+	"github.com/curusarn/resh/record"
+	"go.uber.org/zap"
+)
+
+type stubNotifier struct {
+	recs []record.V2
+}
+
+func (s *stubNotifier) Notify(rec record.V2) error {
+	s.recs = append(s.recs, rec)
+	return nil
+}
+
+type stubFocus struct {
+	focused bool
+}
+
+func (s stubFocus) IsFocused(term recordint.Terminal) bool {
+	return s.focused
+}
+
+func parts(sessionID, recordID, cmdLine, duration string, exitCode int) (recordint.Collect, recordint.Collect) {
+	part1 := recordint.Collect{
+		SessionID: sessionID,
+		Terminal:  recordint.Terminal{TmuxPane: "%1"},
+		Rec:       record.V2{SessionID: sessionID, RecordID: recordID, CmdLine: cmdLine, PartOne: true},
+	}
+	part2 := recordint.Collect{
+		SessionID: sessionID,
+		Rec:       record.V2{SessionID: sessionID, RecordID: recordID, Duration: duration, ExitCode: exitCode},
+	}
+	return part1, part2
+}
+
+func newTestWatcher(t *testing.T, focused bool) (*watcher, *stubNotifier) {
+	redactor, err := redact.New(cfg.Redaction{
+		Patterns: []cfg.RedactionPattern{{Regex: "--token (\\S+)", Action: cfg.RedactionMask}},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error while creating redactor: %v", err)
+	}
+	notifier := &stubNotifier{}
+	config := cfg.Notify{Enabled: true, MinDurationSeconds: 30, OnlyUnfocused: true}
+	return newWatcher(zap.NewNop().Sugar(), config, notifier, stubFocus{focused}, redactor), notifier
+}
+
+// sender returns function that passes both parts of a record to the watcher synchronously
+func sender(w *watcher) func(part1, part2 recordint.Collect) {
+	return func(part1, part2 recordint.Collect) {
+		w.handle(part1)
+		if rec, term, ok := w.handle(part2); ok {
+			w.notify(rec, term)
+		}
+	}
+}
+
+func TestNotify(t *testing.T) {
+	w, notifier := newTestWatcher(t, false)
+	send := sender(w)
+
+	send(parts("s1", "r1", "sleep 1", "1.0", 0))
+	if len(notifier.recs) != 0 {
+		t.Fatalf("Short command should not trigger notification: %+v", notifier.recs)
+	}
+	send(parts("s1", "r2", "make --token abc", "42.5", 2))
+	if len(notifier.recs) != 1 {
+		t.Fatalf("Long command should trigger notification")
+	}
+	rec := notifier.recs[0]
+	if rec.CmdLine == "make --token abc" || !strings.HasPrefix(rec.CmdLine, "make --token ") {
+		t.Fatalf("Command line should be redacted: %s", rec.CmdLine)
+	}
+	if rec.ExitCode != 2 || rec.Duration != "42.5" || rec.PartOne || rec.PartsNotMerged {
+		t.Fatalf("Record was not merged properly: %+v", rec)
+	}
+
+	// second part without first part
+	_, part2 := parts("s2", "r3", "make", "100", 0)
+	send(recordint.Collect{SessionID: "other"}, part2)
+	if len(notifier.recs) != 1 {
+		t.Fatalf("Second part without first part should not trigger notification")
+	}
+}
+
+func TestNotifyFocused(t *testing.T) {
+	w, notifier := newTestWatcher(t, true)
+	send := sender(w)
+	send(parts("s1", "r1", "make", "100", 0))
+	if len(notifier.recs) != 0 {
+		t.Fatalf("Command in focused terminal should not trigger notification")
+	}
+	w.config.OnlyUnfocused = false
+	send(parts("s1", "r2", "make", "100", 0))
+	if len(notifier.recs) != 1 {
+		t.Fatalf("Command in focused terminal should trigger notification when OnlyUnfocused is off")
+	}
+}
+
+func TestMessage(t *testing.T) {
+	title, body := message(record.V2{CmdLine: "make", ExitCode: 1, Duration: "65.4"})
+	if title != "Command failed (exit code 1) after 1m5s" || body != "make" {
+		t.Fatalf("Unexpected message: '%s' '%s'", title, body)
+	}
+	title, _ = message(record.V2{CmdLine: "make", Duration: "30"})
+	if title != "Command finished after 30s" {
+		t.Fatalf("Unexpected title: '%s'", title)
+	}
+}
+
+func TestCommand(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "output.json")
+	script := filepath.Join(dir, "notify.sh")
+	err := os.WriteFile(script, []byte("#!/bin/sh\ncat > "+output+"\n"), 0700)
+	if err != nil {
+		t.Fatalf("Unexpected error while writing script: %v", err)
+	}
+	err = Command{Path: script}.Notify(record.V2{CmdLine: "make", Duration: "42"})
+	if err != nil {
+		t.Fatalf("Unexpected error while running command: %v", err)
+	}
+	jsn, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("Unexpected error while reading output: %v", err)
+	}
+	if !strings.Contains(string(jsn), `"cmdLine":"make"`) {
+		t.Fatalf("Command should get the record as JSON on stdin, got: %s", jsn)
+	}
+
+	err = Command{Path: filepath.Join(dir, "missing")}.Notify(record.V2{})
+	if err == nil {
+		t.Fatal("Expected error for missing command")
+	}
+}
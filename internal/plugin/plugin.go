@@ -0,0 +1,135 @@
+// Package plugin sends merged records to external programs and named pipes as JSON Lines
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+
+	"github.com/curusarn/resh/internal/bus"
+	"github.com/curusarn/resh/internal/cfg"
+	"github.com/curusarn/resh/record"
+	"go.uber.org/zap"
+)
+
+// plugins are reconnected with exponential backoff
+const (
+	minBackoff = 1 * time.Second
+	maxBackoff = 1 * time.Minute
+)
+
+// Plugin writes records from its queue to the plugin
+// Slow plugins never block the writer of history - the bus drops records when the queue is full
+type Plugin struct {
+	sugar  *zap.SugaredLogger
+	config cfg.Plugin
+
+	queue <-chan record.V2
+}
+
+// New creates plugin reading records from the queue - use Run to start writing to it
+func New(sugar *zap.SugaredLogger, config cfg.Plugin, queue <-chan record.V2) *Plugin {
+	return &Plugin{
+		sugar:  sugar.With("module", "plugin", "plugin", config.Name),
+		config: config,
+		queue:  queue,
+	}
+}
+
+// Go subscribes plugins to merged records and runs them
+func Go(sugar *zap.SugaredLogger, configs []cfg.Plugin, merged *bus.Bus[record.V2]) {
+	for _, config := range configs {
+		p := New(sugar, config, merged.Subscribe("plugin "+config.Name, config.QueueSize))
+		go p.Run()
+	}
+}
+
+// Run connects to the plugin and writes queued records to it - it reconnects when the plugin fails
+func (p *Plugin) Run() {
+	backoff := minBackoff
+	for {
+		written, err := p.connectAndWrite()
+		if written > 0 {
+			backoff = minBackoff
+		}
+		p.sugar.Errorw("Plugin failed - reconnecting", "error", err, "backoff", backoff.String())
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// connectAndWrite returns number of records written before the plugin failed
+func (p *Plugin) connectAndWrite() (int, error) {
+	if p.config.Command != "" {
+		return p.runCommand()
+	}
+	return p.openPipe()
+}
+
+func (p *Plugin) runCommand() (int, error) {
+	cmd := exec.Command(p.config.Command, p.config.Args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return 0, fmt.Errorf("could not get stdin of plugin: %w", err)
+	}
+	err = cmd.Start()
+	if err != nil {
+		return 0, fmt.Errorf("could not start plugin: %w", err)
+	}
+	p.sugar.Infow("Plugin started", "pid", cmd.Process.Pid)
+	exited := make(chan error, 1)
+	go func() {
+		exited <- cmd.Wait()
+	}()
+	written, err := p.write(stdin, exited)
+	stdin.Close()
+	cmd.Process.Kill()
+	return written, err
+}
+
+func (p *Plugin) openPipe() (int, error) {
+	info, err := os.Stat(p.config.Pipe)
+	if os.IsNotExist(err) {
+		err = syscall.Mkfifo(p.config.Pipe, 0600)
+		if err != nil {
+			return 0, fmt.Errorf("could not create named pipe: %w", err)
+		}
+	} else if err != nil {
+		return 0, fmt.Errorf("could not stat named pipe: %w", err)
+	} else if info.Mode()&os.ModeNamedPipe == 0 {
+		return 0, fmt.Errorf("'%s' is not a named pipe", p.config.Pipe)
+	}
+	// blocks until there is a reader
+	file, err := os.OpenFile(p.config.Pipe, os.O_WRONLY, 0)
+	if err != nil {
+		return 0, fmt.Errorf("could not open named pipe: %w", err)
+	}
+	defer file.Close()
+	p.sugar.Infow("Named pipe opened")
+	return p.write(file, nil)
+}
+
+// write records from the queue as JSON Lines until writing fails or the plugin exits
+func (p *Plugin) write(w io.Writer, exited chan error) (int, error) {
+	enc := json.NewEncoder(w)
+	written := 0
+	for {
+		select {
+		case rec := <-p.queue:
+			err := enc.Encode(rec)
+			if err != nil {
+				return written, fmt.Errorf("could not write record: %w", err)
+			}
+			written++
+		case err := <-exited:
+			return written, fmt.Errorf("plugin exited: %v", err)
+		}
+	}
+}
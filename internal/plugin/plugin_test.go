@@ -0,0 +1,90 @@
+package plugin
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/curusarn/resh/internal/cfg"
+	"github.com/curusarn/resh/record"
+	"go.uber.org/zap"
+)
+
+// waitForLines waits until the file has given number of lines
+func waitForLines(t *testing.T, fpath string, count int) []string {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		data, _ := os.ReadFile(fpath)
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		if len(data) > 0 && len(lines) >= count {
+			return lines
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d lines in '%s', got: %s", count, fpath, data)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func checkLines(t *testing.T, lines []string, cmdLines ...string) {
+	for i, line := range lines {
+		var rec record.V2
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatalf("Line is not a valid record: %s", line)
+		}
+		if rec.CmdLine != cmdLines[i] {
+			t.Fatalf("Expected '%s' on line %d, got '%s'", cmdLines[i], i, rec.CmdLine)
+		}
+	}
+}
+
+func TestCommand(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "output.jsonl")
+	script := filepath.Join(dir, "plugin.sh")
+	err := os.WriteFile(script, []byte("#!/bin/sh\ncat >> \"$1\"\n"), 0700)
+	if err != nil {
+		t.Fatalf("Unexpected error while writing script: %v", err)
+	}
+	queue := make(chan record.V2, 10)
+	p := New(zap.NewNop().Sugar(), cfg.Plugin{Name: "cat", Command: script, Args: []string{output}}, queue)
+	go p.Run()
+	queue <- record.V2{CmdLine: "git status"}
+	queue <- record.V2{CmdLine: "make"}
+	checkLines(t, waitForLines(t, output, 2), "git status", "make")
+}
+
+func TestPipe(t *testing.T) {
+	pipe := filepath.Join(t.TempDir(), "records.fifo")
+	queue := make(chan record.V2, 10)
+	p := New(zap.NewNop().Sugar(), cfg.Plugin{Name: "pipe", Pipe: pipe}, queue)
+	go p.Run()
+	queue <- record.V2{CmdLine: "git status"}
+	queue <- record.V2{CmdLine: "make"}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := os.Stat(pipe); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Named pipe was not created")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	file, err := os.Open(pipe)
+	if err != nil {
+		t.Fatalf("Unexpected error while opening named pipe: %v", err)
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	var lines []string
+	for len(lines) < 2 && scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	checkLines(t, lines, "git status", "make")
+}
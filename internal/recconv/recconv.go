@@ -35,3 +35,34 @@ func LegacyToV1(r *record.Legacy) *record.V1 {
 		PartsNotMerged: !r.PartsMerged,
 	}
 }
+
+// V1ToV2 converts record to V2 - git branch, commit and toplevel are left empty
+func V1ToV2(r *record.V1) *record.V2 {
+	return &record.V2{
+		Deleted:  r.Deleted,
+		Favorite: r.Favorite,
+
+		CmdLine:  r.CmdLine,
+		ExitCode: r.ExitCode,
+
+		DeviceID:  r.DeviceID,
+		SessionID: r.SessionID,
+		RecordID:  r.RecordID,
+
+		Home:    r.Home,
+		Pwd:     r.Pwd,
+		RealPwd: r.RealPwd,
+
+		Device: r.Device,
+
+		GitOriginRemote: r.GitOriginRemote,
+
+		Time:     r.Time,
+		Duration: r.Duration,
+
+		PartOne:        r.PartOne,
+		PartsNotMerged: r.PartsNotMerged,
+
+		SessionExit: r.SessionExit,
+	}
+}
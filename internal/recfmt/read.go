@@ -0,0 +1,257 @@
+package recfmt
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/curusarn/resh/record"
+)
+
+// maximal length of one line in history file
+const maxLineLength = 1024 * 1024
+
+var bashTimestampRegex = regexp.MustCompile(`^#([0-9]+)$`)
+var zshExtendedRegex = regexp.MustCompile(`^: ([0-9]+):([0-9]+);(.*)$`)
+
+// zsh escapes some bytes of non-ASCII characters in history file - see unmetafy
+const zshMeta = 0x83
+
+// Read records from reader in given format
+// Only fields present in the format are filled in
+// Entries without timestamp are skipped - returns number of skipped entries
+func Read(r io.Reader, format Format) ([]record.V2, int, error) {
+	switch format {
+	case JSONLines:
+		return readJSONLines(r)
+	case CSV:
+		return readCSV(r)
+	case Bash:
+		return readBash(r)
+	case Zsh:
+		return readZsh(r)
+	default:
+		return nil, 0, fmt.Errorf("unknown format '%s'", format)
+	}
+}
+
+func newScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineLength)
+	return scanner
+}
+
+// readJSONLines also reads RESH history files where each line is prefixed by record version
+func readJSONLines(r io.Reader) ([]record.V2, int, error) {
+	var recs []record.V2
+	skipped := 0
+	scanner := newScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 {
+			continue
+		}
+		if strings.HasPrefix(line, "v1{") || strings.HasPrefix(line, "v2{") {
+			line = line[2:]
+		}
+		var rec record.V2
+		err := json.Unmarshal([]byte(line), &rec)
+		if err != nil {
+			return nil, 0, fmt.Errorf("could not decode line %d: %w", lineNum, err)
+		}
+		if _, err := parseEpoch(rec.Time); err != nil {
+			skipped++
+			continue
+		}
+		recs = append(recs, rec)
+	}
+	return recs, skipped, scanner.Err()
+}
+
+func readCSV(r io.Reader) ([]record.V2, int, error) {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err != nil {
+		return nil, 0, fmt.Errorf("could not read header: %w", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[name] = i
+	}
+	for _, required := range []string{"time", "cmdLine"} {
+		if _, found := columns[required]; !found {
+			return nil, 0, fmt.Errorf("missing required column '%s'", required)
+		}
+	}
+	get := func(row []string, name string) string {
+		idx, found := columns[name]
+		if !found || idx >= len(row) {
+			return ""
+		}
+		return row[idx]
+	}
+
+	var recs []record.V2
+	skipped := 0
+	for {
+		row, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("could not read row: %w", err)
+		}
+		rec := record.V2{
+			Time:            get(row, "time"),
+			Duration:        get(row, "duration"),
+			CmdLine:         get(row, "cmdLine"),
+			Pwd:             get(row, "pwd"),
+			RealPwd:         get(row, "realPwd"),
+			Home:            get(row, "home"),
+			GitOriginRemote: get(row, "gitOriginRemote"),
+			GitBranch:       get(row, "gitBranch"),
+			GitCommit:       get(row, "gitCommit"),
+			GitToplevel:     get(row, "gitToplevel"),
+			Device:          get(row, "device"),
+			DeviceID:        get(row, "deviceID"),
+			SessionID:       get(row, "sessionID"),
+			RecordID:        get(row, "recordID"),
+		}
+		if exitCode := get(row, "exitCode"); exitCode != "" {
+			rec.ExitCode, err = strconv.Atoi(exitCode)
+			if err != nil {
+				return nil, 0, fmt.Errorf("could not parse exit code: %w", err)
+			}
+		}
+		if env := get(row, "env"); env != "" {
+			err = json.Unmarshal([]byte(env), &rec.Env)
+			if err != nil {
+				return nil, 0, fmt.Errorf("could not parse env: %w", err)
+			}
+		}
+		if dirty := get(row, "gitDirty"); dirty != "" {
+			rec.GitDirty, err = strconv.ParseBool(dirty)
+			if err != nil {
+				return nil, 0, fmt.Errorf("could not parse git dirty flag: %w", err)
+			}
+		}
+		if _, err := parseEpoch(rec.Time); err != nil {
+			skipped++
+			continue
+		}
+		recs = append(recs, rec)
+	}
+	return recs, skipped, nil
+}
+
+// readBash reads bash history with timestamp comments
+// #1576199174
+// make install
+// All lines up to the next timestamp belong to the same (multiline) command
+func readBash(r io.Reader) ([]record.V2, int, error) {
+	var recs []record.V2
+	skipped := 0
+	var current *record.V2
+	flush := func() {
+		if current != nil && current.CmdLine != "" {
+			recs = append(recs, *current)
+		}
+		current = nil
+	}
+	scanner := newScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if match := bashTimestampRegex.FindStringSubmatch(line); match != nil {
+			flush()
+			secs, err := strconv.ParseInt(match[1], 10, 64)
+			if err != nil {
+				return nil, 0, fmt.Errorf("could not parse timestamp: %w", err)
+			}
+			current = &record.V2{Time: formatEpoch(secs)}
+			continue
+		}
+		if current == nil {
+			if strings.TrimSpace(line) != "" {
+				// command without timestamp
+				skipped++
+			}
+			continue
+		}
+		if current.CmdLine == "" {
+			current.CmdLine = line
+		} else {
+			current.CmdLine += "\n" + line
+		}
+	}
+	flush()
+	return recs, skipped, scanner.Err()
+}
+
+// readZsh reads zsh EXTENDED_HISTORY
+// : 1576270617:0;make install
+// Lines of multiline commands end with backslash
+func readZsh(r io.Reader) ([]record.V2, int, error) {
+	var recs []record.V2
+	skipped := 0
+	continued := false
+	scanner := newScanner(r)
+	for scanner.Scan() {
+		line := unmetafy(scanner.Bytes())
+		if continued && len(recs) > 0 {
+			last := &recs[len(recs)-1]
+			last.CmdLine = strings.TrimSuffix(last.CmdLine, "\\") + "\n" + line
+			continued = strings.HasSuffix(line, "\\")
+			continue
+		}
+		continued = false
+		match := zshExtendedRegex.FindStringSubmatch(line)
+		if match == nil {
+			if strings.TrimSpace(line) != "" {
+				// command without timestamp
+				skipped++
+			}
+			continue
+		}
+		secs, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, 0, fmt.Errorf("could not parse timestamp: %w", err)
+		}
+		duration, err := strconv.ParseInt(match[2], 10, 64)
+		if err != nil {
+			return nil, 0, fmt.Errorf("could not parse duration: %w", err)
+		}
+		recs = append(recs, record.V2{
+			Time:     formatEpoch(secs),
+			Duration: formatEpoch(duration),
+			CmdLine:  match[3],
+		})
+		continued = strings.HasSuffix(match[3], "\\")
+	}
+	return recs, skipped, scanner.Err()
+}
+
+// unmetafy reverts zsh metafication - meta byte followed by the original byte XORed with 32
+func unmetafy(line []byte) string {
+	if bytes.IndexByte(line, zshMeta) == -1 {
+		return string(line)
+	}
+	buf := make([]byte, 0, len(line))
+	for i := 0; i < len(line); i++ {
+		if line[i] == zshMeta && i+1 < len(line) {
+			i++
+			buf = append(buf, line[i]^32)
+			continue
+		}
+		buf = append(buf, line[i])
+	}
+	return string(buf)
+}
@@ -0,0 +1,47 @@
+// Package recfmt converts records from and to other history formats
+package recfmt
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Format of history file
+type Format string
+
+const (
+	// JSONLines is one JSON encoded record per line
+	JSONLines Format = "jsonl"
+	// CSV with header
+	CSV Format = "csv"
+	// Bash history with timestamps (as written by bash when HISTTIMEFORMAT is set)
+	Bash Format = "bash"
+	// Zsh history with EXTENDED_HISTORY
+	Zsh Format = "zsh"
+)
+
+// Formats lists all supported formats
+var Formats = []Format{JSONLines, CSV, Bash, Zsh}
+
+// ParseFormat returns format for given name
+func ParseFormat(name string) (Format, error) {
+	for _, f := range Formats {
+		if string(f) == name {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unknown format '%s' - use one of: %v", name, Formats)
+}
+
+// parseEpoch returns integer part of epoch time in string
+func parseEpoch(tm string) (int64, error) {
+	secs, err := strconv.ParseFloat(tm, 64)
+	if err != nil {
+		return 0, fmt.Errorf("could not parse time: %w", err)
+	}
+	return int64(secs), nil
+}
+
+func formatEpoch(secs int64) string {
+	return fmt.Sprintf("%.4f", float64(secs))
+}
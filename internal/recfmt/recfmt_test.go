@@ -0,0 +1,71 @@
+package recfmt
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/curusarn/resh/record"
+)
+
+func TestRoundTrip(t *testing.T) {
+	recs := []record.V2{
+		{CmdLine: "make install", Time: "1576199174.0000", Duration: "3.0000", ExitCode: 2, Pwd: "/home/user",
+			Env: map[string]string{"AWS_PROFILE": "prod"}},
+		{CmdLine: "for i in 1 2; do\necho $i\ndone", Time: "1576199180.0000", Duration: "0.0000"},
+	}
+	for _, format := range Formats {
+		var buf bytes.Buffer
+		count, err := Write(&buf, format, recs)
+		if err != nil || count != len(recs) {
+			t.Fatalf("%s: expected %d written records, got %d (error: %v)", format, len(recs), count, err)
+		}
+		read, skipped, err := Read(&buf, format)
+		if err != nil || skipped != 0 {
+			t.Fatalf("%s: unexpected error during read: %v (skipped: %d)", format, err, skipped)
+		}
+		if len(read) != len(recs) {
+			t.Fatalf("%s: expected %d records, got %d", format, len(recs), len(read))
+		}
+		for i := range recs {
+			if read[i].CmdLine != recs[i].CmdLine || read[i].Time != recs[i].Time {
+				t.Fatalf("%s: record %d differs - expected %v, got %v", format, i, recs[i], read[i])
+			}
+		}
+		if (format == JSONLines || format == CSV) && read[0].Env["AWS_PROFILE"] != "prod" {
+			t.Fatalf("%s: env was not preserved, got %v", format, read[0].Env)
+		}
+	}
+}
+
+func TestReadNative(t *testing.T) {
+	bash := "ls\n#1576199174\ngit status\n#1576199175\necho a\necho b\n"
+	recs, skipped, err := Read(strings.NewReader(bash), Bash)
+	if err != nil || skipped != 1 || len(recs) != 2 {
+		t.Fatalf("bash: expected 2 records and 1 skipped, got %d and %d (error: %v)", len(recs), skipped, err)
+	}
+	if recs[1].CmdLine != "echo a\necho b" || recs[1].Time != "1576199175.0000" {
+		t.Fatalf("bash: unexpected record %v", recs[1])
+	}
+
+	zsh := ": 1576270617:5;sleep 5\nls\n: 1576270620:0;echo a;\\\necho b\n"
+	recs, skipped, err = Read(strings.NewReader(zsh), Zsh)
+	if err != nil || skipped != 1 || len(recs) != 2 {
+		t.Fatalf("zsh: expected 2 records and 1 skipped, got %d and %d (error: %v)", len(recs), skipped, err)
+	}
+	if recs[0].Duration != "5.0000" || recs[1].CmdLine != "echo a;\necho b" {
+		t.Fatalf("zsh: unexpected records %v", recs)
+	}
+}
+
+func TestReadZshMetafied(t *testing.T) {
+	// '♥' is 0xe2 0x99 0xa5 - zsh writes 0x99 as 0x83 0xb9
+	zsh := ": 1576270617:0;echo \xe2\x83\xb9\xa5\n: 1576270620:0;echo caf\xc3\xa9\n"
+	recs, _, err := Read(strings.NewReader(zsh), Zsh)
+	if err != nil || len(recs) != 2 {
+		t.Fatalf("Expected 2 records, got %d (error: %v)", len(recs), err)
+	}
+	if recs[0].CmdLine != "echo ♥" || recs[1].CmdLine != "echo café" {
+		t.Fatalf("Unexpected command lines: %q, %q", recs[0].CmdLine, recs[1].CmdLine)
+	}
+}
@@ -0,0 +1,145 @@
+package recfmt
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/curusarn/resh/record"
+)
+
+var csvHeader = []string{
+	"time",
+	"duration",
+	"exitCode",
+	"cmdLine",
+	"pwd",
+	"realPwd",
+	"home",
+	"gitOriginRemote",
+	"gitBranch",
+	"gitCommit",
+	"gitToplevel",
+	"gitDirty",
+	"device",
+	"deviceID",
+	"sessionID",
+	"recordID",
+	// JSON object
+	"env",
+}
+
+func csvRow(r *record.V2) []string {
+	return []string{
+		r.Time,
+		r.Duration,
+		strconv.Itoa(r.ExitCode),
+		r.CmdLine,
+		r.Pwd,
+		r.RealPwd,
+		r.Home,
+		r.GitOriginRemote,
+		r.GitBranch,
+		r.GitCommit,
+		r.GitToplevel,
+		strconv.FormatBool(r.GitDirty),
+		r.Device,
+		r.DeviceID,
+		r.SessionID,
+		r.RecordID,
+		csvEnv(r.Env),
+	}
+}
+
+func csvEnv(env map[string]string) string {
+	if len(env) == 0 {
+		return ""
+	}
+	// can't fail for map of strings
+	jsn, _ := json.Marshal(env)
+	return string(jsn)
+}
+
+// Write records to writer in given format
+// Records that can't be represented in the format (e.g. without time) are skipped
+// Returns number of written records
+func Write(w io.Writer, format Format, recs []record.V2) (int, error) {
+	switch format {
+	case JSONLines:
+		return writeJSONLines(w, recs)
+	case CSV:
+		return writeCSV(w, recs)
+	case Bash:
+		return writeBash(w, recs)
+	case Zsh:
+		return writeZsh(w, recs)
+	default:
+		return 0, fmt.Errorf("unknown format '%s'", format)
+	}
+}
+
+func writeJSONLines(w io.Writer, recs []record.V2) (int, error) {
+	enc := json.NewEncoder(w)
+	for i := range recs {
+		err := enc.Encode(&recs[i])
+		if err != nil {
+			return i, fmt.Errorf("could not encode record: %w", err)
+		}
+	}
+	return len(recs), nil
+}
+
+func writeCSV(w io.Writer, recs []record.V2) (int, error) {
+	cw := csv.NewWriter(w)
+	err := cw.Write(csvHeader)
+	if err != nil {
+		return 0, fmt.Errorf("could not write header: %w", err)
+	}
+	for i := range recs {
+		err = cw.Write(csvRow(&recs[i]))
+		if err != nil {
+			return i, fmt.Errorf("could not write record: %w", err)
+		}
+	}
+	cw.Flush()
+	return len(recs), cw.Error()
+}
+
+// writeBash writes timestamp comment before each command
+// Bash reads multiline commands back correctly only with lithist and cmdhist enabled
+func writeBash(w io.Writer, recs []record.V2) (int, error) {
+	bw := bufio.NewWriter(w)
+	count := 0
+	for _, rec := range recs {
+		secs, err := parseEpoch(rec.Time)
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(bw, "#%d\n%s\n", secs, rec.CmdLine)
+		count++
+	}
+	return count, bw.Flush()
+}
+
+// writeZsh writes ': <start>:<duration>;<command>' lines
+// Newlines in multiline commands are escaped by backslash the same way zsh does it
+func writeZsh(w io.Writer, recs []record.V2) (int, error) {
+	bw := bufio.NewWriter(w)
+	count := 0
+	for _, rec := range recs {
+		secs, err := parseEpoch(rec.Time)
+		if err != nil {
+			continue
+		}
+		// duration is optional
+		duration, _ := parseEpoch(rec.Duration)
+		cmdLine := strings.ReplaceAll(rec.CmdLine, "\n", "\\\n")
+		fmt.Fprintf(bw, ": %d:%d;%s\n", secs, duration, cmdLine)
+		count++
+	}
+	return count, bw.Flush()
+}
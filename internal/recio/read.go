@@ -14,7 +14,7 @@ import (
 	"go.uber.org/zap"
 )
 
-func (r *RecIO) ReadAndFixFile(fpath string, maxErrors int) ([]record.V1, error) {
+func (r *RecIO) ReadAndFixFile(fpath string, maxErrors int) ([]record.V2, error) {
 	recs, decodeErrs, err := r.ReadFile(fpath)
 	if err != nil {
 		return nil, err
@@ -69,8 +69,8 @@ func (r *RecIO) ReadAndFixFile(fpath string, maxErrors int) ([]record.V1, error)
 	return recs, nil
 }
 
-func (r *RecIO) ReadFile(fpath string) ([]record.V1, []error, error) {
-	var recs []record.V1
+func (r *RecIO) ReadFile(fpath string) ([]record.V2, []error, error) {
+	var recs []record.V2
 	file, err := os.Open(fpath)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to open history file: %w", err)
@@ -106,7 +106,7 @@ func (r *RecIO) ReadFile(fpath string) ([]record.V1, []error, error) {
 	return recs, decodeErrs, nil
 }
 
-func (r *RecIO) decodeLine(line string) (*record.V1, error) {
+func (r *RecIO) decodeLine(line string) (*record.V2, error) {
 	idx := strings.Index(line, "{")
 	if idx == -1 {
 		return nil, fmt.Errorf("no opening brace found")
@@ -114,20 +114,27 @@ func (r *RecIO) decodeLine(line string) (*record.V1, error) {
 	schema := line[:idx]
 	jsn := line[idx:]
 	switch schema {
+	case "v2":
+		var rec record.V2
+		err := decodeAnyRecord(jsn, &rec)
+		if err != nil {
+			return nil, err
+		}
+		return &rec, nil
 	case "v1":
 		var rec record.V1
 		err := decodeAnyRecord(jsn, &rec)
 		if err != nil {
 			return nil, err
 		}
-		return &rec, nil
+		return recconv.V1ToV2(&rec), nil
 	case "":
 		var rec record.Legacy
 		err := decodeAnyRecord(jsn, &rec)
 		if err != nil {
 			return nil, err
 		}
-		return recconv.LegacyToV1(&rec), nil
+		return recconv.V1ToV2(recconv.LegacyToV1(&rec)), nil
 	default:
 		return nil, fmt.Errorf("unknown record schema/type '%s'", schema)
 	}
@@ -8,7 +8,7 @@ import (
 	"github.com/curusarn/resh/record"
 )
 
-func (r *RecIO) OverwriteFile(fpath string, recs []record.V1) error {
+func (r *RecIO) OverwriteFile(fpath string, recs []record.V2) error {
 	file, err := os.Create(fpath)
 	if err != nil {
 		return fmt.Errorf("could not create/truncate file: %w", err)
@@ -24,7 +24,7 @@ func (r *RecIO) OverwriteFile(fpath string, recs []record.V1) error {
 	return nil
 }
 
-func (r *RecIO) AppendToFile(fpath string, recs []record.V1) error {
+func (r *RecIO) AppendToFile(fpath string, recs []record.V2) error {
 	file, err := os.OpenFile(fpath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		return fmt.Errorf("could not open/create file: %w", err)
@@ -40,9 +40,9 @@ func (r *RecIO) AppendToFile(fpath string, recs []record.V1) error {
 	return nil
 }
 
-func writeRecords(file *os.File, recs []record.V1) error {
+func writeRecords(file *os.File, recs []record.V2) error {
 	for _, rec := range recs {
-		jsn, err := encodeV1Record(rec)
+		jsn, err := encodeV2Record(rec)
 		if err != nil {
 			return fmt.Errorf("could not encode record: %w", err)
 		}
@@ -54,8 +54,8 @@ func writeRecords(file *os.File, recs []record.V1) error {
 	return nil
 }
 
-func encodeV1Record(rec record.V1) ([]byte, error) {
-	version := []byte("v1")
+func encodeV2Record(rec record.V2) ([]byte, error) {
+	version := []byte("v2")
 	jsn, err := json.Marshal(rec)
 	if err != nil {
 		return nil, fmt.Errorf("failed to encode json: %w", err)
@@ -9,8 +9,21 @@ type Collect struct {
 	// session watching
 	SessionPID int
 	Shell      string
+	// notifications about long running commands - only sent with the first part
+	Terminal Terminal
 
-	Rec record.V1
+	Rec record.V2
+}
+
+// Terminal identifies the window and tmux pane of the session
+// It's used to check if the session is focused
+type Terminal struct {
+	// X11 window of the terminal emulator ($WINDOWID) and its display ($DISPLAY)
+	WindowID string
+	Display  string
+	// tmux server ($TMUX) and pane ($TMUX_PANE)
+	Tmux     string
+	TmuxPane string
 }
 
 type Postcollect struct {
@@ -0,0 +1,39 @@
+package recordint
+
+import (
+	"strings"
+	"unicode"
+)
+
+// FlagName identifies a flag of a saved record
+type FlagName string
+
+const (
+	// FlagDeleted marks records that should not show up anywhere (e.g. leaked secrets)
+	FlagDeleted FlagName = "deleted"
+	// FlagFavorite marks records that should be ranked higher in search
+	FlagFavorite FlagName = "favorite"
+)
+
+// Flag sets or unsets a flag of already saved records
+// The flag applies to all records with the command line - search shows them as one result
+type Flag struct {
+	CmdLine string
+	Name    FlagName
+	Value   bool
+}
+
+// IsValid returns true if the flag name is known
+func (f Flag) IsValid() bool {
+	return f.Name == FlagDeleted || f.Name == FlagFavorite
+}
+
+// Matches returns true if the flag applies to record with given command line
+func (f Flag) Matches(cmdLine string) bool {
+	return TrimCmdLine(cmdLine) == TrimCmdLine(f.CmdLine)
+}
+
+// TrimCmdLine removes trailing whitespace the same way search does when deduplicating records
+func TrimCmdLine(cmdLine string) string {
+	return strings.TrimRightFunc(cmdLine, unicode.IsSpace)
+}
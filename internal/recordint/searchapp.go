@@ -13,15 +13,21 @@ type SearchApp struct {
 	IsRaw     bool
 	SessionID string
 	DeviceID  string
+	RecordID  string
 
 	CmdLine         string
 	Host            string
 	Pwd             string
 	Home            string // helps us to collapse /home/user to tilde
 	GitOriginRemote string
+	GitBranch       string
 	ExitCode        int
+	Favorite        bool
+	// captured environment variables - see record.V2
+	Env map[string]string
 
-	Time float64
+	Time     float64
+	Duration float64
 
 	// file index
 	Idx int
@@ -35,22 +41,37 @@ func NewSearchAppFromCmdLine(cmdLine string) SearchApp {
 }
 
 // The error handling here could be better
-func NewSearchApp(sugar *zap.SugaredLogger, r *record.V1) SearchApp {
+func NewSearchApp(sugar *zap.SugaredLogger, r *record.V2) SearchApp {
 	time, err := strconv.ParseFloat(r.Time, 64)
 	if err != nil {
 		sugar.Errorw("Error while parsing time as float", zap.Error(err),
 			"time", time)
 	}
+	// duration is missing for commands that are still running
+	var duration float64
+	if r.Duration != "" {
+		duration, err = strconv.ParseFloat(r.Duration, 64)
+		if err != nil {
+			sugar.Errorw("Error while parsing duration as float", zap.Error(err),
+				"duration", r.Duration)
+		}
+	}
 	return SearchApp{
 		IsRaw:     false,
 		SessionID: r.SessionID,
+		DeviceID:  r.DeviceID,
+		RecordID:  r.RecordID,
 		CmdLine:   r.CmdLine,
 		Host:      r.Device,
 		Pwd:       r.Pwd,
 		Home:      r.Home,
 		// TODO: is this the right place to normalize the git remote?
 		GitOriginRemote: normalize.GitRemote(sugar, r.GitOriginRemote),
+		GitBranch:       r.GitBranch,
 		ExitCode:        r.ExitCode,
+		Favorite:        r.Favorite,
+		Env:             r.Env,
 		Time:            time,
+		Duration:        duration,
 	}
 }
@@ -82,3 +82,58 @@ func LoadCmdLinesFromBashFile(sugar *zap.SugaredLogger, fname string) histlist.H
 	}
 	return hl
 }
+
+// LoadCmdLinesFromFishFile loads cmdlines from fish history file
+func LoadCmdLinesFromFishFile(sugar *zap.SugaredLogger, fname string) histlist.Histlist {
+	hl := histlist.New(sugar)
+	file, err := os.Open(fname)
+	if err != nil {
+		sugar.Error("Failed to open fish history file - skipping reading fish history", zap.Error(err))
+		return hl
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		// fish format (YAML-like)
+		// - cmd: make install
+		//   when: 1576199174
+		//   paths:
+		//     - install
+		if !strings.HasPrefix(line, "- cmd: ") {
+			// when, paths or other fields => skip
+			continue
+		}
+		cmd := unescapeFishCmd(strings.TrimPrefix(line, "- cmd: "))
+		if len(cmd) == 0 {
+			continue
+		}
+		hl.AddCmdLine(cmd)
+	}
+	return hl
+}
+
+// unescapeFishCmd decodes newlines and backslashes escaped by fish
+func unescapeFishCmd(cmd string) string {
+	if !strings.Contains(cmd, "\\") {
+		return cmd
+	}
+	var sb strings.Builder
+	for i := 0; i < len(cmd); i++ {
+		if cmd[i] == '\\' && i+1 < len(cmd) {
+			switch cmd[i+1] {
+			case 'n':
+				sb.WriteByte('\n')
+				i++
+				continue
+			case '\\':
+				sb.WriteByte('\\')
+				i++
+				continue
+			}
+		}
+		sb.WriteByte(cmd[i])
+	}
+	return sb.String()
+}
@@ -0,0 +1,33 @@
+package records
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestLoadCmdLinesFromFishFile(t *testing.T) {
+	fname := path.Join(t.TempDir(), "fish_history")
+	data := `- cmd: make install
+  when: 1576199174
+  paths:
+    - install
+- cmd: echo "a\nb" \\ c
+  when: 1576199175
+`
+	if err := os.WriteFile(fname, []byte(data), 0644); err != nil {
+		t.Fatalf("Test setup failed: %v", err)
+	}
+	hl := LoadCmdLinesFromFishFile(zap.NewNop().Sugar(), fname)
+	expected := []string{"make install", "echo \"a\nb\" \\ c"}
+	if len(hl.List) != len(expected) {
+		t.Fatalf("Expected %d cmdlines, got %v", len(expected), hl.List)
+	}
+	for i, cmd := range expected {
+		if hl.List[i] != cmd {
+			t.Fatalf("Unexpected cmdline %d: expected %q, got %q", i, cmd, hl.List[i])
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package searchapp
+
+import (
+	"time"
+
+	"github.com/curusarn/resh/internal/recordint"
+)
+
+// Filter limits which records are searched
+// Zero values don't filter anything
+type Filter struct {
+	Pwd string
+	// GitOriginRemote needs to be normalized
+	GitOriginRemote string
+	Host            string
+	ExitCode        *int
+
+	Since time.Time
+	Until time.Time
+}
+
+// IsEmpty returns true if the filter doesn't filter anything
+func (f Filter) IsEmpty() bool {
+	return f.Pwd == "" && f.GitOriginRemote == "" && f.Host == "" && f.ExitCode == nil &&
+		f.Since.IsZero() && f.Until.IsZero()
+}
+
+// Match returns true if the record passes the filter
+// Raw records don't have any metadata so they only pass empty filter
+func (f Filter) Match(r recordint.SearchApp) bool {
+	if f.IsEmpty() {
+		return true
+	}
+	if r.IsRaw {
+		return false
+	}
+	if f.Pwd != "" && r.Pwd != f.Pwd {
+		return false
+	}
+	if f.GitOriginRemote != "" && r.GitOriginRemote != f.GitOriginRemote {
+		return false
+	}
+	if f.Host != "" && r.Host != f.Host {
+		return false
+	}
+	if f.ExitCode != nil && r.ExitCode != *f.ExitCode {
+		return false
+	}
+	if !f.Since.IsZero() && r.Time < float64(f.Since.Unix()) {
+		return false
+	}
+	if !f.Until.IsZero() && r.Time >= float64(f.Until.Unix()) {
+		return false
+	}
+	return true
+}
+
+// withTimeRange returns the filter further limited to given time range - zero times mean no limit
+func (f Filter) withTimeRange(since, until time.Time) Filter {
+	if !since.IsZero() && since.After(f.Since) {
+		f.Since = since
+	}
+	if !until.IsZero() && (f.Until.IsZero() || until.Before(f.Until)) {
+		f.Until = until
+	}
+	return f
+}
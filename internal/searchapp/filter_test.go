@@ -0,0 +1,83 @@
+package searchapp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/curusarn/resh/internal/recordint"
+)
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.Local)
+	data := map[string]time.Time{
+		"3d":               now.Add(-3 * 24 * time.Hour),
+		"12h":              now.Add(-12 * time.Hour),
+		"2024-01-31":       time.Date(2024, 1, 31, 0, 0, 0, 0, time.Local),
+		"2024-01-31 14:00": time.Date(2024, 1, 31, 14, 0, 0, 0, time.Local),
+	}
+	for input, expected := range data {
+		tm, err := ParseTime(input, now)
+		if err != nil {
+			t.Fatalf("Unexpected error while parsing '%s': %v", input, err)
+		}
+		if !tm.Equal(expected) {
+			t.Fatalf("Incorrect time for '%s': expected %v, got %v", input, expected, tm)
+		}
+	}
+	for _, input := range []string{"", "d", "3x", "yesterday", "2024-13-01"} {
+		_, err := ParseTime(input, now)
+		if err == nil {
+			t.Fatalf("Expected error while parsing '%s'", input)
+		}
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	rec := recordint.SearchApp{
+		CmdLine:  "make",
+		Host:     "laptop",
+		Pwd:      "/home/user/resh",
+		ExitCode: 2,
+		Time:     float64(time.Date(2024, 1, 31, 12, 0, 0, 0, time.Local).Unix()),
+	}
+	raw := recordint.NewSearchAppFromCmdLine("make")
+	zero := 0
+	two := 2
+
+	if !(Filter{}).Match(rec) || !(Filter{}).Match(raw) {
+		t.Fatal("Empty filter should match everything")
+	}
+	if (Filter{Host: "laptop"}).Match(raw) {
+		t.Fatal("Raw records should not match non-empty filter")
+	}
+	if !(Filter{Host: "laptop", Pwd: "/home/user/resh", ExitCode: &two}).Match(rec) {
+		t.Fatal("Filter should match record with the same host, pwd and exit code")
+	}
+	if (Filter{ExitCode: &zero}).Match(rec) {
+		t.Fatal("Filter should not match record with different exit code")
+	}
+	since := time.Date(2024, 2, 1, 0, 0, 0, 0, time.Local)
+	if (Filter{Since: since}).Match(rec) || !(Filter{Until: since}).Match(rec) {
+		t.Fatal("Time range filter does not work")
+	}
+}
+
+func TestTimeRangeStart(t *testing.T) {
+	// Thursday
+	now := time.Date(2024, 5, 16, 12, 30, 0, 0, time.Local)
+	data := map[string]time.Time{
+		TimeRangeAll:   {},
+		TimeRangeToday: time.Date(2024, 5, 16, 0, 0, 0, 0, time.Local),
+		TimeRangeWeek:  time.Date(2024, 5, 13, 0, 0, 0, 0, time.Local),
+		TimeRangeMonth: time.Date(2024, 5, 1, 0, 0, 0, 0, time.Local),
+	}
+	for timeRange, expected := range data {
+		if start := TimeRangeStart(timeRange, now); !start.Equal(expected) {
+			t.Errorf("Incorrect start of %s: expected %v, got %v", timeRange, expected, start)
+		}
+	}
+	sunday := time.Date(2024, 5, 19, 23, 0, 0, 0, time.Local)
+	if start := TimeRangeStart(TimeRangeWeek, sunday); !start.Equal(data[TimeRangeWeek]) {
+		t.Errorf("Week should start on Monday, got %v", start)
+	}
+}
@@ -0,0 +1,131 @@
+package searchapp
+
+import (
+	"unicode"
+)
+
+// fuzzy scoring - inspired by fzf
+const (
+	fuzzyScoreMatch       = 16
+	fuzzyBonusBoundary    = 8
+	fuzzyBonusCamelCase   = 6
+	fuzzyBonusConsecutive = 4
+	fuzzyPenaltyGapStart  = 3
+	fuzzyPenaltyGapExtend = 1
+)
+
+func isWordChar(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// fuzzyBonus is the bonus for matching rune r that follows rune prev
+func fuzzyBonus(prev, r rune) int {
+	if !isWordChar(prev) && isWordChar(r) {
+		return fuzzyBonusBoundary
+	}
+	if unicode.IsLower(prev) && unicode.IsUpper(r) {
+		return fuzzyBonusCamelCase
+	}
+	return 0
+}
+
+// fuzzyMatch finds pattern as a subsequence of str
+// Matching is case-insensitive unless the pattern contains upper case characters (smart case)
+// Returns byte ranges of matched characters and match quality in (0, 1] - 1 is the best possible match
+func fuzzyMatch(str, pattern string) ([][]int, float64, bool) {
+	caseSensitive := false
+	for _, r := range pattern {
+		if unicode.IsUpper(r) {
+			caseSensitive = true
+			break
+		}
+	}
+	pat := []rune(pattern)
+	if len(pat) == 0 {
+		return nil, 0, false
+	}
+	text := make([]rune, 0, len(str))
+	offsets := make([]int, 0, len(str)+1)
+	for i, r := range str {
+		text = append(text, r)
+		offsets = append(offsets, i)
+	}
+	offsets = append(offsets, len(str))
+	eq := func(r, p rune) bool {
+		if caseSensitive {
+			return r == p
+		}
+		return unicode.ToLower(r) == p
+	}
+
+	// forward pass finds the first occurrence of the subsequence
+	pi, start, end := 0, -1, -1
+	for i, r := range text {
+		if !eq(r, pat[pi]) {
+			continue
+		}
+		if start == -1 {
+			start = i
+		}
+		pi++
+		if pi == len(pat) {
+			end = i + 1
+			break
+		}
+	}
+	if end == -1 {
+		return nil, 0, false
+	}
+	// backward pass shortens the match from the left
+	pi = len(pat) - 1
+	for i := end - 1; i >= start; i-- {
+		if eq(text[i], pat[pi]) {
+			pi--
+			if pi < 0 {
+				start = i
+				break
+			}
+		}
+	}
+
+	var ranges [][]int
+	score := 0
+	pi = 0
+	last := -1
+	for i := start; i < end && pi < len(pat); i++ {
+		if !eq(text[i], pat[pi]) {
+			continue
+		}
+		score += fuzzyScoreMatch
+		prev := ' '
+		if i > 0 {
+			prev = text[i-1]
+		}
+		score += fuzzyBonus(prev, text[i])
+		if last != -1 {
+			if i == last+1 {
+				score += fuzzyBonusConsecutive
+			} else {
+				score -= fuzzyPenaltyGapStart + fuzzyPenaltyGapExtend*(i-last-2)
+			}
+		}
+		if len(ranges) > 0 && ranges[len(ranges)-1][1] == offsets[i] {
+			ranges[len(ranges)-1][1] = offsets[i+1]
+		} else {
+			ranges = append(ranges, []int{offsets[i], offsets[i+1]})
+		}
+		last = i
+		pi++
+	}
+
+	// best match is a consecutive run starting at a word boundary
+	maxScore := len(pat)*(fuzzyScoreMatch+fuzzyBonusConsecutive) + fuzzyBonusBoundary - fuzzyBonusConsecutive
+	quality := float64(score) / float64(maxScore)
+	if quality > 1 {
+		quality = 1
+	}
+	if quality < 0.05 {
+		quality = 0.05
+	}
+	return ranges, quality, true
+}
@@ -0,0 +1,53 @@
+package searchapp
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/curusarn/resh/internal/recordint"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	ranges, _, ok := fuzzyMatch("git checkout main", "gco")
+	if !ok {
+		t.Fatal("Expected 'gco' to match 'git checkout main'")
+	}
+	expected := [][]int{{0, 1}, {4, 5}, {9, 10}}
+	if !reflect.DeepEqual(ranges, expected) {
+		t.Fatalf("Unexpected ranges: %v", ranges)
+	}
+	if _, _, ok := fuzzyMatch("git checkout", "gx"); ok {
+		t.Fatal("Expected no match")
+	}
+	if _, _, ok := fuzzyMatch("git checkout", "GIT"); ok {
+		t.Fatal("Upper case pattern should be case sensitive")
+	}
+	ranges, _, ok = fuzzyMatch("Švestka make", "šm")
+	if !ok || !reflect.DeepEqual(ranges, [][]int{{0, 2}, {9, 10}}) {
+		t.Fatalf("Unexpected ranges for unicode input: %v", ranges)
+	}
+
+	// shorter, consecutive and word boundary matches score higher
+	_, best, _ := fuzzyMatch("make test", "make")
+	_, boundary, _ := fuzzyMatch("git checkout", "gch")
+	_, scattered, _ := fuzzyMatch("gohack", "gch")
+	if best != 1 || boundary <= scattered {
+		t.Fatalf("Unexpected quality: best %v, boundary %v, scattered %v", best, boundary, scattered)
+	}
+}
+
+func TestFuzzyQuery(t *testing.T) {
+	q := NewRawQueryFromString(`gco "main" /^git/ -gx`, true, false)
+	if !q.terms[0].fuzzy || q.terms[1].fuzzy || q.terms[2].fuzzy || q.excluded[0].fuzzy {
+		t.Fatalf("Only plain terms should be fuzzy: %v %v", q.terms, q.excluded)
+	}
+	rec := recordint.SearchApp{IsRaw: true, CmdLine: "git checkout"}
+	itm, err := NewRawItemFromRecordForQuery(rec, NewRawQueryFromString("gco", true, false), false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := highlightMatch("g") + "it " + highlightMatch("c") + "heck" + highlightMatch("o") + "ut"
+	if itm.CmdLineWithColor != expected {
+		t.Fatalf("Unexpected highlighting: %q", itm.CmdLineWithColor)
+	}
+}
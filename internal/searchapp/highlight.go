@@ -1,94 +1,124 @@
 package searchapp
 
 import (
-	"strconv"
+	"sort"
 	"strings"
+
+	"github.com/curusarn/resh/internal/cfg"
 )
 
-func cleanHighlight(str string) string {
-	prefix := "\033["
-
-	invert := "\033[7;1m"
-	invertGreen := "\033[32;7;1m"
-	end := "\033[0m"
-	replace := []string{invert, invertGreen, end}
-	for i := 30; i < 48; i++ {
-		base := prefix + strconv.Itoa(i)
-		normal := base + "m"
-		bold := base + ";1m"
-		replace = append(replace, normal, bold)
+var theme = cfg.Themes[cfg.ThemeDark]
+
+// SetTheme sets colors used by the search app
+func SetTheme(t cfg.Theme) {
+	theme = t
+}
+
+func applyStyle(style, str string) string {
+	if style == "" {
+		return str
 	}
-	if strings.Contains(str, prefix) == false {
+	return "\033[" + style + "m" + str + "\033[0m"
+}
+
+// cleanHighlight removes all SGR escape sequences (e.g. "\033[35;1m") from str
+func cleanHighlight(str string) string {
+	const prefix = "\033["
+	if !strings.Contains(str, prefix) {
 		return str
 	}
-	for _, escSeq := range replace {
-		str = strings.ReplaceAll(str, escSeq, "")
+	var sb strings.Builder
+	sb.Grow(len(str))
+	for {
+		start := strings.Index(str, prefix)
+		if start == -1 {
+			break
+		}
+		end := start + len(prefix)
+		for end < len(str) && (str[end] == ';' || (str[end] >= '0' && str[end] <= '9')) {
+			end++
+		}
+		if end == len(str) || str[end] != 'm' {
+			// not a SGR sequence - keep it
+			sb.WriteString(str[:end])
+			str = str[end:]
+			continue
+		}
+		sb.WriteString(str[:start])
+		str = str[end+1:]
 	}
-	return str
+	sb.WriteString(str)
+	return sb.String()
 }
 
 func highlightHeader(str string) string {
-	underline := "\033[4m"
-	end := "\033[0m"
 	// no clean highlight
-	return underline + str + end
+	return applyStyle(theme.Header, str)
 }
 
 func highlightStatus(str string) string {
-	invert := "\033[7;1m"
-	end := "\033[0m"
-	return invert + cleanHighlight(str) + end
+	return applyStyle(theme.Status, cleanHighlight(str))
 }
 
 func highlightSelected(str string) string {
-	// template "\033[3%d;%dm"
-	// invertGreen := "\033[32;7;1m"
-	invert := "\033[7;1m"
-	end := "\033[0m"
-	return invert + cleanHighlight(str) + end
+	return applyStyle(theme.Selected, cleanHighlight(str))
 }
 
 func highlightDate(str string) string {
-	// template "\033[3%d;%dm"
-	yellowNormal := "\033[33m"
-	end := "\033[0m"
-	return yellowNormal + cleanHighlight(str) + end
+	return applyStyle(theme.Date, cleanHighlight(str))
 }
 
 func highlightHost(str string) string {
-	// template "\033[3%d;%dm"
-	redNormal := "\033[31m"
-	end := "\033[0m"
-	return redNormal + cleanHighlight(str) + end
+	return applyStyle(theme.Host, cleanHighlight(str))
 }
 
 func highlightPwd(str string) string {
-	// template "\033[3%d;%dm"
-	blueBold := "\033[34;1m"
-	end := "\033[0m"
-	return blueBold + cleanHighlight(str) + end
+	return applyStyle(theme.Pwd, cleanHighlight(str))
 }
 
 func highlightMatch(str string) string {
-	// template "\033[3%d;%dm"
-	magentaBold := "\033[35;1m"
-	end := "\033[0m"
-	return magentaBold + cleanHighlight(str) + end
+	return applyStyle(theme.Match, cleanHighlight(str))
+}
+
+// highlightMatches highlights given ranges of str
+// Ranges can overlap and they don't need to be sorted
+func highlightMatches(str string, ranges [][]int) string {
+	if len(ranges) == 0 {
+		return str
+	}
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i][0] < ranges[j][0] })
+	var sb strings.Builder
+	pos := 0
+	for i := 0; i < len(ranges); i++ {
+		start, end := ranges[i][0], ranges[i][1]
+		// merge overlapping and adjacent ranges
+		for i+1 < len(ranges) && ranges[i+1][0] <= end {
+			i++
+			if ranges[i][1] > end {
+				end = ranges[i][1]
+			}
+		}
+		if start < pos {
+			start = pos
+		}
+		sb.WriteString(str[pos:start])
+		sb.WriteString(highlightMatch(str[start:end]))
+		pos = end
+	}
+	sb.WriteString(str[pos:])
+	return sb.String()
 }
 
 func highlightWarn(str string) string {
-	// template "\033[3%d;%dm"
-	// orangeBold := "\033[33;1m"
-	redBold := "\033[31;1m"
-	end := "\033[0m"
-	return redBold + cleanHighlight(str) + end
+	return applyStyle(theme.Warn, cleanHighlight(str))
 }
 
 func highlightGit(str string) string {
-	// template "\033[3%d;%dm"
-	greenBold := "\033[32;1m"
-	end := "\033[0m"
-	return greenBold + cleanHighlight(str) + end
+	return applyStyle(theme.Git, cleanHighlight(str))
+}
+
+func highlightFavorite(str string) string {
+	return applyStyle(theme.Favorite, cleanHighlight(str))
 }
 
 // DoHighlightHeader .
@@ -106,12 +136,3 @@ func DoHighlightString(str string, minLength int) string {
 	}
 	return highlightSelected(str)
 }
-
-// EXTRAS
-
-func highlightModeTitle(str string) string {
-	// template "\033[3%d;%dm"
-	greenNormal := "\033[32;1m"
-	end := "\033[0m"
-	return greenNormal + cleanHighlight(str) + end
-}
@@ -0,0 +1,34 @@
+package searchapp
+
+import (
+	"testing"
+
+	"github.com/curusarn/resh/internal/cfg"
+)
+
+func TestCleanHighlight(t *testing.T) {
+	data := map[string]string{
+		"git status":                               "git status",
+		"\033[35;1mgit\033[0m status":              "git status",
+		"\033[38;5;208;1mgit\033[0m \033[4mstatus": "git status",
+		"\033[38;2;255;135;0mgit\033[0m":           "git",
+		"\033[2Jgit":                               "\033[2Jgit",
+		"git\033[":                                 "git\033[",
+	}
+	for str, expected := range data {
+		if clean := cleanHighlight(str); clean != expected {
+			t.Errorf("Unexpected result for %q: %q", str, clean)
+		}
+	}
+}
+
+func TestSetTheme(t *testing.T) {
+	defer SetTheme(cfg.Themes[cfg.ThemeDark])
+	SetTheme(cfg.Themes[cfg.ThemeNoColor])
+	if str := highlightDate("2024-01-01"); str != "2024-01-01" {
+		t.Errorf("Expected no color for date, got %q", str)
+	}
+	if str := highlightMatch("\033[33mgit\033[0m"); str != "\033[1mgit\033[0m" {
+		t.Errorf("Unexpected match highlight: %q", str)
+	}
+}
@@ -1,13 +1,16 @@
 package searchapp
 
 import (
+	"errors"
 	"fmt"
 	"math"
+	"path"
 	"strconv"
 	"strings"
 	"time"
 	"unicode"
 
+	"github.com/curusarn/resh/internal/cfg"
 	"github.com/curusarn/resh/internal/recordint"
 	"golang.org/x/exp/utf8string"
 )
@@ -32,6 +35,13 @@ type Item struct {
 	sameGitRepo bool
 	exitCode    int
 
+	// [F]
+	Favorite bool
+	// Stats of all records represented by this item
+	Stats Stats
+	// Record is the best scoring record represented by this item
+	Record recordint.SearchApp
+
 	// Shown in TUI
 	CmdLineWithColor string
 	CmdLine          string
@@ -40,14 +50,23 @@ type Item struct {
 
 	Score float64
 
+	// number of query terms and how many of them matched
+	termCount int
+	hitCount  int
+
 	Key string
 	// cmdLineRaw string
 }
 
 // ItemColumns holds rendered columns
 type ItemColumns struct {
-	DateWithColor string
-	Date          string
+	// last seen
+	DateWithColor      string
+	Date               string
+	FirstSeenWithColor string
+	FirstSeen          string
+	// number of executions
+	Count string
 
 	// [host:]pwd
 	differentHost bool
@@ -71,6 +90,12 @@ type ItemColumns struct {
 	// cmdLineRaw string
 }
 
+// MatchesQuery returns true if any query term matched the command line
+// Items always match queries without terms
+func (i Item) MatchesQuery() bool {
+	return i.termCount == 0 || i.hitCount > 0
+}
+
 func (i Item) less(i2 Item) bool {
 	// reversed order
 	return i.Score > i2.Score
@@ -119,10 +144,38 @@ func (i Item) DrawStatusLine(compactRendering bool, printedLineLength, realLineL
 	pwdTilde := strings.Replace(i.pwd, i.home, "~", 1)
 
 	separator := "    "
-	stLine := timeString + separator + i.host + ":" + pwdTilde + separator + i.CmdLine
+	stLine := timeString + separator + i.host + ":" + pwdTilde + separator
+	if i.Stats.Count > 1 {
+		stLine += i.drawStats(timeFormat) + separator
+	}
+	stLine += i.CmdLine
 	return splitStatusLineToLines(stLine, printedLineLength, realLineLength)
 }
 
+// drawStats describes how many times, where and when the command was executed
+func (i Item) drawStats(timeFormat string) string {
+	plural := func(n int, what string) string {
+		if n == 1 {
+			return "1 " + what
+		}
+		return strconv.Itoa(n) + " " + what + "s"
+	}
+	stats := "executed " + plural(i.Stats.Count, "time") +
+		" in " + plural(i.Stats.DirCount, "dir") +
+		" on " + plural(i.Stats.DeviceCount, "device")
+	if i.Stats.FirstSeen != 0 {
+		stats += ", first " + unixToTime(i.Stats.FirstSeen).Format(timeFormat) +
+			", last " + unixToTime(i.Stats.LastSeen).Format(timeFormat)
+	}
+	return stats
+}
+
+func unixToTime(t float64) time.Time {
+	secs := int64(t)
+	nsecs := int64((t - float64(secs)) * 1e9)
+	return time.Unix(secs, nsecs)
+}
+
 // GetEmptyStatusLine .
 func GetEmptyStatusLine(printedLineLength, realLineLength int) []string {
 	return splitStatusLineToLines("- no result selected -", printedLineLength, realLineLength)
@@ -147,17 +200,29 @@ func (i Item) DrawItemColumns(compactRendering bool, debug bool) ItemColumns {
 
 	// DISPLAY
 	// DISPLAY > date
-	secs := int64(i.time)
-	nsecs := int64((i.time - float64(secs)) * 1e9)
-	tm := time.Unix(secs, nsecs)
+	lastSeen := i.time
+	if i.Stats.LastSeen != 0 {
+		lastSeen = i.Stats.LastSeen
+	}
+	tm := unixToTime(lastSeen)
 
 	var date string
+	firstSeen := ""
 	if compactRendering {
 		date = formatTimeRelativeShort(tm) + " "
 	} else {
 		date = formatTimeRelativeLong(tm) + " "
+		if i.Stats.FirstSeen != 0 {
+			firstSeen = formatTimeRelativeLong(unixToTime(i.Stats.FirstSeen)) + " "
+		}
 	}
 	dateWithColor := highlightDate(date)
+	firstSeenWithColor := highlightDate(firstSeen)
+	// DISPLAY > count
+	count := ""
+	if i.Stats.Count > 0 {
+		count = strconv.Itoa(i.Stats.Count) + "x "
+	}
 	// DISPLAY > location
 	// DISPLAY > location > host
 	host := ""
@@ -179,6 +244,10 @@ func (i Item) DrawItemColumns(compactRendering bool, debug bool) ItemColumns {
 		flags += " G"
 		flagsWithColor += " " + highlightGit("G")
 	}
+	if i.Favorite {
+		flags += " F"
+		flagsWithColor += " " + highlightFavorite("F")
+	}
 	if i.exitCode != 0 {
 		flags += " E" + strconv.Itoa(i.exitCode)
 		flagsWithColor += " " + highlightWarn("E"+strconv.Itoa(i.exitCode))
@@ -187,21 +256,30 @@ func (i Item) DrawItemColumns(compactRendering bool, debug bool) ItemColumns {
 	// flags += " <" + record.GitOriginRemote + ">"
 	// flagsWithColor += " <" + record.GitOriginRemote + ">"
 	return ItemColumns{
-		Date:             date,
-		DateWithColor:    dateWithColor,
-		Host:             host,
-		PwdTilde:         pwdTilde,
-		samePwd:          i.samePwd,
-		differentHost:    i.differentHost,
-		Flags:            flags,
-		FlagsWithColor:   flagsWithColor,
-		CmdLine:          i.CmdLine,
-		CmdLineWithColor: i.CmdLineWithColor,
+		Date:               date,
+		DateWithColor:      dateWithColor,
+		FirstSeenWithColor: firstSeenWithColor,
+		FirstSeen:          firstSeen,
+		Count:              count,
+		Host:               host,
+		PwdTilde:           pwdTilde,
+		samePwd:            i.samePwd,
+		differentHost:      i.differentHost,
+		Flags:              flags,
+		FlagsWithColor:     flagsWithColor,
+		CmdLine:            i.CmdLine,
+		CmdLineWithColor:   i.CmdLineWithColor,
 		// score:             i.score,
 		Key: i.Key,
 	}
 }
 
+// Mark adds a flag showing that the item is selected
+func (ic *ItemColumns) Mark() {
+	ic.Flags += " *"
+	ic.FlagsWithColor += " " + highlightMatch("*")
+}
+
 func minInt(values ...int) int {
 	min := math.MaxInt32
 	for _, val := range values {
@@ -258,11 +336,18 @@ func produceLocation(length int, host string, pwdTilde string, differentHost boo
 }
 
 // ProduceLine ...
-func (ic ItemColumns) ProduceLine(dateLength int, locationLength int, flagsLength int, header bool, showDate bool, debug bool) (string, int, error) {
+// Count and first seen columns are not shown when their length is zero
+func (ic ItemColumns) ProduceLine(dateLength int, firstSeenLength int, countLength int, locationLength int, flagsLength int, header bool, showDate bool, debug bool) (string, int, error) {
 	var err error
 	line := ""
 	if showDate {
 		line += strings.Repeat(" ", dateLength-len(ic.Date)) + ic.DateWithColor
+		if firstSeenLength > 0 {
+			line += strings.Repeat(" ", firstSeenLength-len(ic.FirstSeen)) + ic.FirstSeenWithColor
+		}
+	}
+	if countLength > 0 {
+		line += strings.Repeat(" ", countLength-len(ic.Count)) + ic.Count
 	}
 	// LOCATION
 	locationWithColor := produceLocation(locationLength, ic.Host, ic.PwdTilde, ic.differentHost, ic.samePwd, debug)
@@ -283,7 +368,7 @@ func (ic ItemColumns) ProduceLine(dateLength int, locationLength int, flagsLengt
 	}
 	line += spacer + ic.CmdLineWithColor
 
-	length := dateLength + locationLength + flagsLength + len(spacer) + len(ic.CmdLine)
+	length := dateLength + firstSeenLength + countLength + locationLength + flagsLength + len(spacer) + len(ic.CmdLine)
 	return line, length, err
 }
 
@@ -329,14 +414,67 @@ func rightCutPadString(str string, newLen int) string {
 	return str
 }
 
-// proper match for path is when whole directory is matched
 // proper match for command is when term matches word delimited by whitespace
-func properMatch(str, term, padChar string) bool {
-	return strings.Contains(padChar+str+padChar, padChar+term+padChar)
+func properMatch(str string, matches [][]int) bool {
+	for _, m := range matches {
+		if (m[0] == 0 || str[m[0]-1] == ' ') && (m[1] == len(str) || str[m[1]] == ' ') {
+			return true
+		}
+	}
+	return false
+}
+
+// sameParentDir returns true for sibling directories and for the parent directory
+func sameParentDir(recordPwd, pwd string) bool {
+	if recordPwd == "" || pwd == "" {
+		return false
+	}
+	parent := path.Dir(pwd)
+	if parent == "/" || parent == "." {
+		// everything is a sibling in root
+		return false
+	}
+	return recordPwd == parent || path.Dir(recordPwd) == parent
+}
+
+// recencyScore halves every RecencyHalfLifeHours
+func recencyScore(ranking cfg.Ranking, ageSeconds float64) float64 {
+	if ranking.Recency == 0 || ranking.RecencyHalfLifeHours <= 0 {
+		return 0
+	}
+	if ageSeconds < 0 {
+		ageSeconds = 0
+	}
+	return ranking.Recency * math.Exp2(-ageSeconds/(ranking.RecencyHalfLifeHours*3600))
+}
+
+// envScore is the share of current environment variables that had the same value when the command was executed
+func envScore(ranking cfg.Ranking, env, recordEnv map[string]string) float64 {
+	if ranking.Env == 0 || len(env) == 0 || len(recordEnv) == 0 {
+		return 0
+	}
+	same := 0
+	for name, value := range env {
+		if recordEnv[name] == value {
+			same++
+		}
+	}
+	return ranking.Env * float64(same) / float64(len(env))
 }
 
+// frequencyScore grows with log2 of the execution count - first execution adds nothing
+func frequencyScore(ranking cfg.Ranking, count int) float64 {
+	if count <= 1 {
+		return 0
+	}
+	return ranking.Frequency * math.Log2(float64(count))
+}
+
+// errNoMatch is returned when the record is filtered out by the query
+var errNoMatch = errors.New("no match for given record and query")
+
 func trimCmdLine(cmdLine string) string {
-	return strings.TrimRightFunc(cmdLine, unicode.IsSpace)
+	return recordint.TrimCmdLine(cmdLine)
 }
 
 func replaceNewLines(cmdLine string) string {
@@ -353,23 +491,8 @@ func NewItemFromRecordForQuery(record recordint.SearchApp, query Query, debug bo
 	const properMatchScore = 0.501      // 0.33 * 1.51
 	const hitScoreConsecutive = 0.00302 // 0.002 * 1.51
 
-	// context score weight 1
-	// Host penalty
-	var actualPwdScore = 0.9
-	var sameGitRepoScore = 0.8
-	var nonZeroExitCodeScorePenalty = 0.4
-	var differentHostScorePenalty = 0.2
-
-	reduceHostPenalty := false
-	if reduceHostPenalty {
-		actualPwdScore = 0.9
-		sameGitRepoScore = 0.7
-		nonZeroExitCodeScorePenalty = 0.4
-		differentHostScorePenalty = 0.1
-	}
-
-	const timeScoreCoef = 1e-13
-	// nonZeroExitCodeScorePenalty + differentHostScorePenalty
+	// context score weights are configurable - see cfg.Ranking
+	ranking := query.ranking
 
 	// Trim trailing whitespace before highlighting
 	trimmedCmdLine := trimCmdLine(record.CmdLine)
@@ -377,25 +500,31 @@ func NewItemFromRecordForQuery(record recordint.SearchApp, query Query, debug bo
 	// KEY for deduplication
 	key := trimmedCmdLine
 
+	if !query.filter(record) {
+		return Item{}, errNoMatch
+	}
+
 	score := 0.0
 	anyHit := false
-	cmd := trimmedCmdLine
+	hitCount := 0
+	var matches [][]int
 	for _, term := range query.terms {
-		c := strings.Count(record.CmdLine, term)
-		if c > 0 {
+		termMatches, quality := term.find(trimmedCmdLine)
+		if len(termMatches) > 0 {
 			anyHit = true
-			score += hitScore + hitScoreConsecutive*float64(c)
-			if properMatch(cmd, term, " ") {
+			hitCount++
+			score += hitScore*quality + hitScoreConsecutive*float64(len(termMatches))
+			if properMatch(trimmedCmdLine, termMatches) {
 				score += properMatchScore
 			}
-			cmd = strings.ReplaceAll(cmd, term, highlightMatch(term))
+			matches = append(matches, termMatches...)
 		}
 	}
 	// DISPLAY > cmdline
 
 	// cmd := "<" + strings.ReplaceAll(record.CmdLine, "\n", ";") + ">"
 	cmdLine := replaceNewLines(trimmedCmdLine)
-	cmdLineWithColor := replaceNewLines(cmd)
+	cmdLineWithColor := replaceNewLines(highlightMatches(trimmedCmdLine, matches))
 
 	if record.IsRaw {
 		return Item{
@@ -405,6 +534,8 @@ func NewItemFromRecordForQuery(record recordint.SearchApp, query Query, debug bo
 			CmdLine:          cmdLine,
 			CmdLineWithColor: cmdLineWithColor,
 			Score:            score,
+			termCount:        len(query.terms),
+			hitCount:         hitCount,
 			Key:              key,
 		}, nil
 	}
@@ -421,27 +552,41 @@ func NewItemFromRecordForQuery(record recordint.SearchApp, query Query, debug bo
 	if record.Pwd == query.pwd {
 		anyHit = true
 		samePwd = true
-		score += actualPwdScore
+		score += ranking.Pwd
 	} else if sameGitRepo {
 		anyHit = true
-		score += sameGitRepoScore
+		score += ranking.GitRepo
+	} else if sameParentDir(record.Pwd, query.pwd) {
+		score += ranking.ParentDir
+	}
+	// in monorepos the remote is the same for everyone - branch narrows it down
+	if sameGitRepo && len(query.gitBranch) != 0 && query.gitBranch == record.GitBranch {
+		score += ranking.GitBranch
 	}
+	if len(query.sessionID) != 0 && query.sessionID == record.SessionID {
+		score += ranking.Session
+	}
+	score += envScore(ranking, query.env, record.Env)
 
 	differentHost := false
 	if record.Host != query.host {
 		differentHost = true
-		score -= differentHostScorePenalty
+		score -= ranking.DifferentHostPenalty
 	}
 	// errorExitStatus := false
 	if record.ExitCode != 0 {
 		// errorExitStatus = true
-		score -= nonZeroExitCodeScorePenalty
+		score -= ranking.NonZeroExitCodePenalty
+	}
+	if record.Favorite {
+		score += ranking.Favorite
 	}
 	_ = anyHit
 	// if score <= 0 && !anyHit {
 	//	return Item{}, errors.New("no match for given record and query")
 	// }
-	score += record.Time * timeScoreCoef
+	score += recencyScore(ranking, query.now-record.Time)
+	score += record.Time * ranking.TimeCoef
 
 	it := Item{
 		time: record.Time,
@@ -454,10 +599,14 @@ func NewItemFromRecordForQuery(record recordint.SearchApp, query Query, debug bo
 
 		sameGitRepo:      sameGitRepo,
 		exitCode:         record.ExitCode,
+		Favorite:         record.Favorite,
+		Record:           record,
 		CmdLineOut:       record.CmdLine,
 		CmdLine:          cmdLine,
 		CmdLineWithColor: cmdLineWithColor,
 		Score:            score,
+		termCount:        len(query.terms),
+		hitCount:         hitCount,
 		Key:              key,
 	}
 	return it, nil
@@ -465,7 +614,9 @@ func NewItemFromRecordForQuery(record recordint.SearchApp, query Query, debug bo
 
 // GetHeader returns header columns
 func GetHeader(compactRendering bool) ItemColumns {
-	date := "TIME "
+	date := "LAST "
+	firstSeen := "FIRST "
+	count := "RUNS "
 	host := "HOST"
 	dir := "DIRECTORY"
 	if compactRendering {
@@ -474,15 +625,18 @@ func GetHeader(compactRendering bool) ItemColumns {
 	flags := " FLAGS"
 	cmdLine := "COMMAND-LINE"
 	return ItemColumns{
-		Date:             date,
-		DateWithColor:    date,
-		Host:             host,
-		PwdTilde:         dir,
-		samePwd:          false,
-		Flags:            flags,
-		FlagsWithColor:   flags,
-		CmdLine:          cmdLine,
-		CmdLineWithColor: cmdLine,
+		Date:               date,
+		DateWithColor:      date,
+		FirstSeenWithColor: firstSeen,
+		FirstSeen:          firstSeen,
+		Count:              count,
+		Host:               host,
+		PwdTilde:           dir,
+		samePwd:            false,
+		Flags:              flags,
+		FlagsWithColor:     flags,
+		CmdLine:            cmdLine,
+		CmdLineWithColor:   cmdLine,
 		// score:             i.score,
 		Key: "_HEADERS_",
 	}
@@ -504,7 +658,7 @@ type RawItem struct {
 // NewRawItemFromRecordForQuery creates new item from record based on given query
 //
 //	returns error if the query doesn't match the record
-func NewRawItemFromRecordForQuery(record recordint.SearchApp, terms []string, debug bool) (RawItem, error) {
+func NewRawItemFromRecordForQuery(record recordint.SearchApp, query Query, debug bool) (RawItem, error) {
 	const hitScore = 1.0
 	const hitScoreConsecutive = 0.01
 	const properMatchScore = 0.3
@@ -517,16 +671,20 @@ func NewRawItemFromRecordForQuery(record recordint.SearchApp, terms []string, de
 	// KEY for deduplication
 	key := trimmedCmdLine
 
+	if !query.filter(record) {
+		return RawItem{}, errNoMatch
+	}
+
 	score := 0.0
-	cmd := trimmedCmdLine
-	for _, term := range terms {
-		c := strings.Count(record.CmdLine, term)
-		if c > 0 {
-			score += hitScore + hitScoreConsecutive*float64(c)
-			if properMatch(cmd, term, " ") {
+	var matches [][]int
+	for _, term := range query.terms {
+		termMatches, quality := term.find(trimmedCmdLine)
+		if len(termMatches) > 0 {
+			score += hitScore*quality + hitScoreConsecutive*float64(len(termMatches))
+			if properMatch(trimmedCmdLine, termMatches) {
 				score += properMatchScore
 			}
-			cmd = strings.ReplaceAll(cmd, term, highlightMatch(term))
+			matches = append(matches, termMatches...)
 		}
 	}
 	score += record.Time * timeScoreCoef
@@ -534,7 +692,7 @@ func NewRawItemFromRecordForQuery(record recordint.SearchApp, terms []string, de
 
 	// cmd := "<" + strings.ReplaceAll(record.CmdLine, "\n", ";") + ">"
 	cmdLine := replaceNewLines(trimmedCmdLine)
-	cmdLineWithColor := replaceNewLines(cmd)
+	cmdLineWithColor := replaceNewLines(highlightMatches(trimmedCmdLine, matches))
 
 	it := RawItem{
 		CmdLineOut:       record.CmdLine,
@@ -2,6 +2,12 @@ package searchapp
 
 import (
 	"testing"
+	"time"
+
+	"github.com/curusarn/resh/internal/cfg"
+	"github.com/curusarn/resh/internal/normalize"
+	"github.com/curusarn/resh/internal/recordint"
+	"go.uber.org/zap"
 )
 
 // TestLeftCutPadString
@@ -129,3 +135,100 @@ func TestRightCutLeftPadString(t *testing.T) {
 		t.Fatal("Incorrect right pad from ♥♥♥♥ to '  ♥♥♥♥'")
 	}
 }
+
+func TestSameGitBranchRanksHigher(t *testing.T) {
+	sugar := zap.NewNop().Sugar()
+	remote := normalize.GitRemote(sugar, "git@github.com:curusarn/resh.git")
+	qctx := QueryContext{Host: "laptop", Pwd: "/home/user/resh", GitOriginRemote: remote, GitBranch: "feature"}
+	query := NewQueryFromString(sugar, "make", qctx, cfg.DefaultRanking, false, false)
+	rec := recordint.SearchApp{
+		CmdLine:         "make build",
+		Host:            "laptop",
+		Pwd:             "/home/user/resh/cmd",
+		GitOriginRemote: remote,
+		GitBranch:       "main",
+	}
+	other, err := NewItemFromRecordForQuery(rec, query, false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	rec.GitBranch = "feature"
+	same, err := NewItemFromRecordForQuery(rec, query, false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if same.Score <= other.Score {
+		t.Fatalf("Expected command from the same branch to score higher: %f <= %f", same.Score, other.Score)
+	}
+	rec.GitOriginRemote = normalize.GitRemote(sugar, "git@github.com:curusarn/other.git")
+	otherRepo, err := NewItemFromRecordForQuery(rec, query, false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if otherRepo.Score >= other.Score {
+		t.Fatalf("Same branch name in a different repository should not score higher")
+	}
+}
+
+func TestRankingWeights(t *testing.T) {
+	sugar := zap.NewNop().Sugar()
+	qctx := QueryContext{Host: "laptop", Pwd: "/home/user/projects/resh", SessionID: "s1"}
+	here := recordint.SearchApp{CmdLine: "make", Host: "laptop", Pwd: "/home/user/projects/resh"}
+	sibling := recordint.SearchApp{CmdLine: "make", Host: "laptop", Pwd: "/home/user/projects/other", SessionID: "s1"}
+	elsewhere := recordint.SearchApp{CmdLine: "make", Host: "laptop", Pwd: "/tmp"}
+	score := func(ranking cfg.Ranking, rec recordint.SearchApp) float64 {
+		itm, err := NewItemFromRecordForQuery(rec, NewQueryFromString(sugar, "make", qctx, ranking, false, false), false)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return itm.Score
+	}
+
+	ranking := cfg.DefaultRanking
+	if !(score(ranking, here) > score(ranking, sibling) && score(ranking, sibling) > score(ranking, elsewhere)) {
+		t.Fatal("Expected current directory to rank above sibling directory and sibling above unrelated directory")
+	}
+	// pwd doesn't dominate when the same session is preferred
+	ranking.Pwd = 0.1
+	ranking.Session = 1
+	if score(ranking, here) >= score(ranking, sibling) {
+		t.Fatal("Expected command from the same session to rank higher with custom weights")
+	}
+
+	ranking = cfg.DefaultRanking
+	now := float64(time.Now().Unix())
+	elsewhere.Time = now
+	fresh := score(ranking, elsewhere)
+	elsewhere.Time = now - ranking.RecencyHalfLifeHours*3600
+	halfLife := score(ranking, elsewhere)
+	if diff := fresh - halfLife; diff < ranking.Recency/2*0.99 || diff > ranking.Recency/2*1.01 {
+		t.Fatalf("Expected recency score to halve after half-life, got difference %f", diff)
+	}
+
+	if frequencyScore(ranking, 1) != 0 || frequencyScore(ranking, 4) != 2*ranking.Frequency {
+		t.Fatal("Unexpected frequency score")
+	}
+}
+
+func TestSameEnvRanksHigher(t *testing.T) {
+	sugar := zap.NewNop().Sugar()
+	qctx := QueryContext{Host: "laptop", Pwd: "/tmp", Env: map[string]string{"AWS_PROFILE": "prod", "VIRTUAL_ENV": "/venv"}}
+	query := NewQueryFromString(sugar, "aws", qctx, cfg.DefaultRanking, false, false)
+	score := func(env map[string]string) float64 {
+		rec := recordint.SearchApp{CmdLine: "aws s3 ls", Host: "laptop", Pwd: "/home", Env: env}
+		itm, err := NewItemFromRecordForQuery(rec, query, false)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return itm.Score
+	}
+	none := score(nil)
+	staging := score(map[string]string{"AWS_PROFILE": "staging", "VIRTUAL_ENV": "/venv"})
+	prod := score(map[string]string{"AWS_PROFILE": "prod", "VIRTUAL_ENV": "/venv"})
+	if !(prod > staging && staging > none) {
+		t.Fatalf("Expected commands with more matching env variables to rank higher: %f, %f, %f", prod, staging, none)
+	}
+	if diff := prod - none; diff < cfg.DefaultRanking.Env*0.99 || diff > cfg.DefaultRanking.Env*1.01 {
+		t.Fatalf("Expected full env match to add Env weight, got %f", diff)
+	}
+}
@@ -0,0 +1,127 @@
+package searchapp
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/curusarn/resh/internal/recordint"
+	"golang.org/x/exp/utf8string"
+)
+
+func rightCutString(str string, newLen int) string {
+	if newLen <= 0 {
+		return ""
+	}
+	utf8Str := utf8string.NewString(str)
+	if utf8Str.RuneCount() > newLen {
+		return utf8Str.Slice(0, newLen-1) + dots
+	}
+	return str
+}
+
+func formatDuration(seconds float64) string {
+	return time.Duration(seconds * float64(time.Second)).Round(time.Millisecond).String()
+}
+
+// DrawPreview renders full details of the item and commands around it from the same session
+// session holds records of the item's session sorted by time - it is nil while it's being loaded
+func (i Item) DrawPreview(session []recordint.SearchApp, height, width int) []string {
+	if i.isRaw {
+		return []string{rightCutString(i.CmdLineOut, width), "", "- no details for commands from shell history -"}
+	}
+	rec := i.Record
+	var lines []string
+	for _, line := range strings.Split(trimCmdLine(rec.CmdLine), "\n") {
+		lines = append(lines, highlightMatch(rightCutString(line, width)))
+	}
+	lines = append(lines, "")
+
+	const timeFormat = "2006-01-02 15:04:05"
+	separator := "    "
+	exitCode := "Exit code: " + strconv.Itoa(rec.ExitCode)
+	if rec.ExitCode != 0 {
+		exitCode = highlightWarn(exitCode)
+	}
+	pwd := rec.Pwd
+	if rec.Home != "" {
+		pwd = strings.Replace(pwd, rec.Home, "~", 1)
+	}
+	details := []string{
+		exitCode + separator + "Duration: " + formatDuration(rec.Duration) + separator + "Time: " + unixToTime(rec.Time).Format(timeFormat),
+		"Device: " + rec.Host + separator + "Directory: " + pwd,
+	}
+	if rec.GitOriginRemote != "" {
+		git := "Git: " + rec.GitOriginRemote
+		if rec.GitBranch != "" {
+			git += " (" + rec.GitBranch + ")"
+		}
+		details = append(details, git)
+	}
+	if len(rec.Env) > 0 {
+		var names []string
+		for name := range rec.Env {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		var env []string
+		for _, name := range names {
+			env = append(env, name+"="+rec.Env[name])
+		}
+		details = append(details, "Env: "+strings.Join(env, " "))
+	}
+	details = append(details, "Session: "+rec.SessionID+separator+"Record: "+rec.RecordID)
+	for _, line := range details {
+		lines = append(lines, rightCutString(line, width))
+	}
+
+	available := height - len(lines) - 2
+	if available <= 0 {
+		return lines
+	}
+	lines = append(lines, "", highlightHeader("Session history"))
+	if session == nil {
+		return append(lines, "loading ...")
+	}
+	return append(lines, drawSessionContext(session, rec.RecordID, available, width)...)
+}
+
+// drawSessionContext renders up to count commands around the record with given ID
+func drawSessionContext(session []recordint.SearchApp, recordID string, count, width int) []string {
+	idx := -1
+	for j, rec := range session {
+		if rec.RecordID == recordID {
+			idx = j
+			break
+		}
+	}
+	if idx == -1 {
+		return []string{"- record not found in session -"}
+	}
+	start := idx - count/2
+	if start < 0 {
+		start = 0
+	}
+	end := start + count
+	if end > len(session) {
+		end = len(session)
+		start = end - count
+		if start < 0 {
+			start = 0
+		}
+	}
+	var lines []string
+	for j := start; j < end; j++ {
+		prefix := "  "
+		if j == idx {
+			prefix = "> "
+		}
+		line := rightCutString(prefix+replaceNewLines(trimCmdLine(session[j].CmdLine)), width)
+		if j == idx {
+			line = highlightMatch(line)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
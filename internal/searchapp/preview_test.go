@@ -0,0 +1,29 @@
+package searchapp
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/curusarn/resh/internal/recordint"
+)
+
+func TestDrawSessionContext(t *testing.T) {
+	var session []recordint.SearchApp
+	for _, id := range []string{"a", "b", "c", "d", "e"} {
+		session = append(session, recordint.SearchApp{RecordID: id, CmdLine: "cmd " + id})
+	}
+	lines := drawSessionContext(session, "b", 3, 80)
+	expected := []string{"  cmd a", highlightMatch("> cmd b"), "  cmd c"}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Fatalf("Unexpected lines: %q", lines)
+	}
+	// window is shifted at the end of the session
+	lines = drawSessionContext(session, "e", 3, 80)
+	expected = []string{"  cmd c", "  cmd d", highlightMatch("> cmd e")}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Fatalf("Unexpected lines at the end of session: %q", lines)
+	}
+	if len(drawSessionContext(session, "a", 10, 80)) != 5 {
+		t.Fatal("Expected the whole session")
+	}
+}
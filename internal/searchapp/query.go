@@ -1,74 +1,385 @@
 package searchapp
 
 import (
-	"sort"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/curusarn/resh/internal/cfg"
 	"github.com/curusarn/resh/internal/normalize"
+	"github.com/curusarn/resh/internal/recordint"
 	"go.uber.org/zap"
 )
 
 // Query holds information that is used for result scoring
+//
+// Query syntax:
+//
+//	term         command line contains term
+//	"some term"  command line contains phrase (with spaces)
+//	/regex/      command line matches regular expression
+//	-term        command line doesn't contain term (works with all of the above and with fields)
+//	field:value  record field matches value - see qualifierFields
+//
+// In fuzzy mode plain terms match as subsequences (see fuzzyMatch) - phrases, regexes and excluded terms are always exact
 type Query struct {
-	terms           []string
+	// terms are used for scoring and highlighting
+	terms []term
+	// excluded terms and qualifiers filter records
+	excluded   []term
+	qualifiers []qualifier
+
 	host            string
 	pwd             string
 	gitOriginRemote string
+	gitBranch       string
+	sessionID       string
+	env             map[string]string
 	// pwdTilde string
+
+	ranking cfg.Ranking
+	now     float64
+}
+
+// QueryContext is the current context of the user - it is used for ranking
+type QueryContext struct {
+	Host            string
+	Pwd             string
+	GitOriginRemote string
+	GitBranch       string
+	SessionID       string
+	// Env holds current values of CaptureEnv environment variables
+	Env map[string]string
+}
+
+// term is a plain substring, a fuzzy pattern or a regular expression
+type term struct {
+	text  string
+	re    *regexp.Regexp
+	fuzzy bool
+}
+
+// find returns non-overlapping non-empty matches of the term in str and match quality in (0, 1]
+// Only fuzzy matches can have quality lower than 1
+func (t term) find(str string) ([][]int, float64) {
+	if t.fuzzy {
+		matches, quality, ok := fuzzyMatch(str, t.text)
+		if !ok {
+			return nil, 0
+		}
+		return matches, quality
+	}
+	return t.findExact(str), 1
+}
+
+func (t term) findExact(str string) [][]int {
+	if t.re != nil {
+		var matches [][]int
+		for _, m := range t.re.FindAllStringIndex(str, -1) {
+			if m[1] > m[0] {
+				matches = append(matches, m)
+			}
+		}
+		return matches
+	}
+	var matches [][]int
+	offset := 0
+	for {
+		idx := strings.Index(str[offset:], t.text)
+		if idx == -1 {
+			return matches
+		}
+		start := offset + idx
+		offset = start + len(t.text)
+		matches = append(matches, []int{start, offset})
+	}
+}
+
+func (t term) matches(str string) bool {
+	if t.re != nil {
+		return t.re.MatchString(str)
+	}
+	return strings.Contains(str, t.text)
 }
 
-func isValidTerm(term string) bool {
-	if len(term) == 0 {
-		return false
+// qualifierFields are record fields that can be used in query as 'field:value'
+//
+//	pwd:~/projects        directory contains value (~ is the home directory)
+//	host:laptop           host contains value
+//	exit:0                exit code equals value
+//	git:resh              git remote or branch contains value
+//	env:prod              value of any captured environment variable contains value
+//	env:AWS_PROFILE=prod  value of given environment variable contains value
+//	since:3d              command was executed at given time or later (see ParseTime) - after: is an alias
+//	before:2024-05-01     command was executed before given time
+var qualifierFields = map[string]bool{
+	"pwd":    true,
+	"host":   true,
+	"exit":   true,
+	"git":    true,
+	"env":    true,
+	"since":  true,
+	"after":  true,
+	"before": true,
+}
+
+type qualifier struct {
+	field   string
+	value   string
+	exclude bool
+
+	exitCode int
+	time     float64
+}
+
+// newQualifier returns false for invalid values - these are ignored so that partially typed query doesn't break search
+func newQualifier(field, value string, exclude bool, now time.Time) (qualifier, bool) {
+	q := qualifier{field: field, value: value, exclude: exclude}
+	if value == "" {
+		return q, false
 	}
-	if strings.Contains(term, " ") {
-		return false
+	switch field {
+	case "exit":
+		code, err := strconv.Atoi(value)
+		if err != nil {
+			return q, false
+		}
+		q.exitCode = code
+	case "since", "after", "before":
+		tm, err := ParseTime(value, now)
+		if err != nil {
+			return q, false
+		}
+		q.time = float64(tm.Unix())
+	}
+	return q, true
+}
+
+// match returns true if the record passes the qualifier
+// Raw records don't have any metadata so they only pass excluding qualifiers
+func (q qualifier) match(r recordint.SearchApp) bool {
+	if r.IsRaw {
+		return q.exclude
+	}
+	var found bool
+	switch q.field {
+	case "pwd":
+		value := q.value
+		if strings.HasPrefix(value, "~") && r.Home != "" {
+			value = r.Home + value[1:]
+		}
+		found = strings.Contains(r.Pwd, value)
+	case "host":
+		found = strings.Contains(r.Host, q.value)
+	case "exit":
+		found = r.ExitCode == q.exitCode
+	case "git":
+		found = strings.Contains(r.GitOriginRemote, q.value) || strings.Contains(r.GitBranch, q.value)
+	case "env":
+		found = matchEnv(r.Env, q.value)
+	case "since", "after":
+		found = r.Time >= q.time
+	case "before":
+		found = r.Time < q.time
+	}
+	return found != q.exclude
+}
+
+// matchEnv returns true if value of any variable contains value
+// Value like "NAME=value" only checks variable NAME
+func matchEnv(env map[string]string, value string) bool {
+	if eq := strings.IndexByte(value, '='); eq > 0 {
+		envValue, found := env[value[:eq]]
+		return found && strings.Contains(envValue, value[eq+1:])
+	}
+	for _, envValue := range env {
+		if strings.Contains(envValue, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// filter returns true if the record passes all qualifiers and doesn't contain any excluded terms
+func (q Query) filter(r recordint.SearchApp) bool {
+	for _, qual := range q.qualifiers {
+		if !qual.match(r) {
+			return false
+		}
+	}
+	for _, t := range q.excluded {
+		if t.matches(r.CmdLine) {
+			return false
+		}
 	}
 	return true
 }
 
-func filterTerms(terms []string) []string {
-	var newTerms []string
-	for _, term := range terms {
-		if isValidTerm(term) {
-			newTerms = append(newTerms, term)
+// timeRange returns time range the query is limited to - zero times mean no limit
+func (q Query) timeRange() (since, until time.Time) {
+	for _, qual := range q.qualifiers {
+		if qual.exclude {
+			continue
+		}
+		tm := time.Unix(int64(qual.time), 0)
+		switch qual.field {
+		case "since", "after":
+			if since.IsZero() || tm.After(since) {
+				since = tm
+			}
+		case "before":
+			if until.IsZero() || tm.Before(until) {
+				until = tm
+			}
 		}
 	}
-	return newTerms
+	return since, until
 }
 
-// NewQueryFromString .
-func NewQueryFromString(sugar *zap.SugaredLogger, queryInput string, host string, pwd string, gitOriginRemote string, debug bool) Query {
-	terms := strings.Fields(queryInput)
-	var logStr string
-	for _, term := range terms {
-		logStr += " <" + term + ">"
+// token is one part of the query input
+type token struct {
+	text    string
+	exclude bool
+	regex   bool
+	quoted  bool
+	field   string
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n'
+}
+
+// readQuoted reads phrase starting after opening quote at input[start-1]
+// Unterminated phrase ends at the end of the input (user is still typing)
+func readQuoted(input string, start int) (string, int) {
+	end := strings.IndexByte(input[start:], '"')
+	if end == -1 {
+		return input[start:], len(input)
 	}
-	terms = filterTerms(terms)
-	logStr = ""
-	for _, term := range terms {
-		logStr += " <" + term + ">"
+	return input[start : start+end], start + end + 1
+}
+
+// readRegex reads regex starting at opening slash input[start-1]
+// Closing slash needs to be followed by whitespace or end of input so that paths (e.g. /usr/bin) are not regexes
+func readRegex(input string, start int) (string, int, bool) {
+	for i := start; i < len(input); i++ {
+		if input[i] == '\\' {
+			i++
+			continue
+		}
+		if input[i] == '/' && i > start && (i+1 == len(input) || isSpace(input[i+1])) {
+			return input[start:i], i + 1, true
+		}
 	}
-	sort.SliceStable(terms, func(i, j int) bool { return len(terms[i]) < len(terms[j]) })
-	return Query{
-		terms:           terms,
-		host:            host,
-		pwd:             pwd,
-		gitOriginRemote: normalize.GitRemote(sugar, gitOriginRemote),
+	return "", start, false
+}
+
+func readWord(input string, start int) (string, int) {
+	end := start
+	for end < len(input) && !isSpace(input[end]) {
+		end++
+	}
+	return input[start:end], end
+}
+
+func tokenize(input string) []token {
+	var tokens []token
+	i := 0
+	for i < len(input) {
+		if isSpace(input[i]) {
+			i++
+			continue
+		}
+		var tok token
+		if input[i] == '-' && i+1 < len(input) && !isSpace(input[i+1]) {
+			tok.exclude = true
+			i++
+		}
+		switch input[i] {
+		case '"':
+			tok.text, i = readQuoted(input, i+1)
+			tok.quoted = true
+		case '/':
+			text, end, ok := readRegex(input, i+1)
+			if ok {
+				tok.text, i = text, end
+				tok.regex = true
+				break
+			}
+			tok.text, i = readWord(input, i)
+		default:
+			start := i
+			tok.text, i = readWord(input, i)
+			colon := strings.IndexByte(tok.text, ':')
+			if colon == -1 || !qualifierFields[tok.text[:colon]] {
+				break
+			}
+			tok.field = tok.text[:colon]
+			valueStart := start + colon + 1
+			if valueStart < len(input) && input[valueStart] == '"' {
+				tok.text, i = readQuoted(input, valueStart+1)
+			} else {
+				tok.text = tok.text[colon+1:]
+			}
+		}
+		tokens = append(tokens, tok)
 	}
+	return tokens
 }
 
-// GetRawTermsFromString .
-func GetRawTermsFromString(queryInput string, debug bool) []string {
-	terms := strings.Fields(queryInput)
-	var logStr string
-	for _, term := range terms {
-		logStr += " <" + term + ">"
+// parse fills terms, excluded terms and qualifiers of the query from query input
+func (q *Query) parse(queryInput string, fuzzy bool, now time.Time) {
+	for _, tok := range tokenize(queryInput) {
+		if tok.field != "" {
+			qual, ok := newQualifier(tok.field, tok.text, tok.exclude, now)
+			if ok {
+				q.qualifiers = append(q.qualifiers, qual)
+			}
+			continue
+		}
+		if tok.text == "" {
+			continue
+		}
+		t := term{text: tok.text}
+		if tok.regex {
+			re, err := regexp.Compile(tok.text)
+			if err != nil {
+				// probably not finished yet - search for it as is
+				t.text = "/" + tok.text + "/"
+			} else {
+				t.re = re
+			}
+		}
+		if tok.exclude {
+			q.excluded = append(q.excluded, t)
+		} else {
+			t.fuzzy = fuzzy && !tok.regex && !tok.quoted
+			q.terms = append(q.terms, t)
+		}
 	}
-	terms = filterTerms(terms)
-	logStr = ""
-	for _, term := range terms {
-		logStr += " <" + term + ">"
+}
+
+// NewQueryFromString parses query input (see Query) and adds current context and ranking weights to it
+func NewQueryFromString(sugar *zap.SugaredLogger, queryInput string, qctx QueryContext, ranking cfg.Ranking, fuzzy bool, debug bool) Query {
+	now := time.Now()
+	q := Query{
+		host:            qctx.Host,
+		pwd:             qctx.Pwd,
+		gitOriginRemote: normalize.GitRemote(sugar, qctx.GitOriginRemote),
+		gitBranch:       qctx.GitBranch,
+		sessionID:       qctx.SessionID,
+		env:             qctx.Env,
+		ranking:         ranking,
+		now:             float64(now.Unix()),
 	}
-	return terms
+	q.parse(queryInput, fuzzy, now)
+	return q
+}
+
+// NewRawQueryFromString parses query input (see Query) without any context
+func NewRawQueryFromString(queryInput string, fuzzy bool, debug bool) Query {
+	var q Query
+	q.parse(queryInput, fuzzy, time.Now())
+	return q
 }
@@ -0,0 +1,118 @@
+package searchapp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/curusarn/resh/internal/recordint"
+)
+
+func TestParseQuery(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.Local)
+	var q Query
+	q.parse(`git "commit -m" -push /^make (install|test)$/ /usr/bin pwd:"~/my projects" -exit:0 after:3d host: exit:x`, false, now)
+
+	terms := []string{"git", "commit -m", "^make (install|test)$", "/usr/bin"}
+	if len(q.terms) != len(terms) {
+		t.Fatalf("Expected %d terms, got %v", len(terms), q.terms)
+	}
+	for i, text := range terms {
+		if q.terms[i].text != text {
+			t.Fatalf("Unexpected term %d: expected %q, got %q", i, text, q.terms[i].text)
+		}
+	}
+	if q.terms[2].re == nil || q.terms[3].re != nil {
+		t.Fatal("Only /regex/ terms should be regular expressions")
+	}
+	if len(q.excluded) != 1 || q.excluded[0].text != "push" {
+		t.Fatalf("Unexpected excluded terms: %v", q.excluded)
+	}
+	// invalid qualifiers are ignored
+	if len(q.qualifiers) != 3 {
+		t.Fatalf("Expected 3 qualifiers, got %v", q.qualifiers)
+	}
+	if q.qualifiers[0].value != "~/my projects" || !q.qualifiers[1].exclude {
+		t.Fatalf("Unexpected qualifiers: %v", q.qualifiers)
+	}
+	if q.qualifiers[2].time != float64(now.Add(-3*24*time.Hour).Unix()) {
+		t.Fatalf("Unexpected after qualifier: %v", q.qualifiers[2])
+	}
+
+	// unfinished input
+	q = Query{}
+	q.parse(`"git comm /foo(/`, false, now)
+	if len(q.terms) != 1 || q.terms[0].text != "git comm /foo(/" {
+		t.Fatalf("Unexpected terms for unfinished phrase: %v", q.terms)
+	}
+	q = Query{}
+	q.parse(`/foo(/`, false, now)
+	if len(q.terms) != 1 || q.terms[0].re != nil || q.terms[0].text != "/foo(/" {
+		t.Fatalf("Invalid regex should be searched as is: %v", q.terms)
+	}
+}
+
+func TestQueryFilter(t *testing.T) {
+	rec := recordint.SearchApp{
+		CmdLine:  "git push origin main",
+		Home:     "/home/user",
+		Pwd:      "/home/user/projects/resh",
+		Host:     "laptop",
+		ExitCode: 1,
+		Time:     float64(time.Date(2024, 1, 2, 0, 0, 0, 0, time.Local).Unix()),
+		Env:      map[string]string{"AWS_PROFILE": "prod-eu", "KUBECONFIG": "/home/user/.kube/dev"},
+	}
+	data := map[string]bool{
+		"git":                    true,
+		"-push":                  false,
+		`-"push origin"`:         false,
+		"-/^git/":                false,
+		"-/^push/":               true,
+		"pwd:~/projects":         true,
+		"pwd:/tmp":               false,
+		"-pwd:/tmp":              true,
+		"host:lap exit:1":        true,
+		"exit:0":                 false,
+		"after:2024-01-01":       true,
+		"after:2024-01-03":       false,
+		"since:2024-01-01":       true,
+		"before:2024-01-03":      true,
+		"before:2024-01-02":      false,
+		"-before:2024-01-02":     true,
+		"env:prod":               true,
+		"env:AWS_PROFILE=prod":   true,
+		"env:KUBECONFIG=prod":    false,
+		"env:VIRTUAL_ENV=":       false,
+		"-env:dev":               false,
+		"git -exit:1":            false,
+		"unknown:field -missing": true,
+	}
+	for input, expected := range data {
+		q := NewRawQueryFromString(input, false, false)
+		if q.filter(rec) != expected {
+			t.Fatalf("Unexpected filter result for '%s': expected %v", input, expected)
+		}
+	}
+	raw := recordint.SearchApp{IsRaw: true, CmdLine: "git push"}
+	if NewRawQueryFromString("pwd:/tmp", false, false).filter(raw) {
+		t.Fatal("Raw records should not pass qualifiers")
+	}
+	if !NewRawQueryFromString("-pwd:/tmp", false, false).filter(raw) {
+		t.Fatal("Raw records should pass excluding qualifiers")
+	}
+}
+
+func TestHighlightMatches(t *testing.T) {
+	rec := recordint.SearchApp{IsRaw: true, CmdLine: "make install && make test"}
+	itm, err := NewRawItemFromRecordForQuery(rec, NewRawQueryFromString(`/make (install|test)/ "install &&" m`, false, false), false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := highlightMatch("make install &&") + " " + highlightMatch("make test")
+	if itm.CmdLineWithColor != expected {
+		t.Fatalf("Unexpected highlighting: %q", itm.CmdLineWithColor)
+	}
+	_, err = NewRawItemFromRecordForQuery(rec, NewRawQueryFromString("-test", false, false), false)
+	if err == nil {
+		t.Fatal("Expected error for excluded record")
+	}
+}
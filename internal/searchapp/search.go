@@ -0,0 +1,145 @@
+package searchapp
+
+import (
+	"context"
+	"sort"
+
+	"github.com/curusarn/resh/internal/recordint"
+)
+
+// Result of search - the best scoring record for each command line
+type Result struct {
+	Record recordint.SearchApp
+	Score  float64
+	// Stats of all records with the same command line
+	Stats Stats
+}
+
+// Stats are aggregated over all records with the same command line
+type Stats struct {
+	Count     int
+	FirstSeen float64
+	LastSeen  float64
+	// number of distinct directories and devices where the command was executed
+	DirCount    int
+	DeviceCount int
+}
+
+// seenKey is used to count distinct directories and devices of each result without allocating
+type seenKey struct {
+	result int
+	device bool
+	value  string
+}
+
+// add updates stats with the record of result with given index
+func (s *Stats) add(r recordint.SearchApp, result int, seen map[seenKey]bool) {
+	s.Count++
+	if r.Time != 0 {
+		if s.FirstSeen == 0 || r.Time < s.FirstSeen {
+			s.FirstSeen = r.Time
+		}
+		if r.Time > s.LastSeen {
+			s.LastSeen = r.Time
+		}
+	}
+	if r.Pwd != "" {
+		key := seenKey{result: result, value: r.Pwd}
+		if !seen[key] {
+			seen[key] = true
+			s.DirCount++
+		}
+	}
+	if r.Host != "" {
+		key := seenKey{result: result, device: true, value: r.Host}
+		if !seen[key] {
+			seen[key] = true
+			s.DeviceCount++
+		}
+	}
+}
+
+// Options of search
+type Options struct {
+	Filter Filter
+	// OnlyMatching drops records that don't match any of the query terms
+	OnlyMatching bool
+	// Limit number of results - no limit when zero
+	Limit int
+}
+
+// check for cancellation every N records
+const cancelCheckPeriod = 1000
+
+// Search records for query and return results sorted by score
+// Returns error when the context gets canceled
+func Search(ctx context.Context, records []recordint.SearchApp, query Query, opts Options, debug bool) ([]Result, error) {
+	var results []Result
+	resultSet := make(map[string]int)
+	seen := make(map[seenKey]bool)
+	// time range from the query is checked before the expensive scoring
+	filter := opts.Filter.withTimeRange(query.timeRange())
+	for i, rec := range records {
+		if i%cancelCheckPeriod == 0 && ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if !filter.Match(rec) {
+			continue
+		}
+		itm, err := NewItemFromRecordForQuery(rec, query, debug)
+		if err != nil || (opts.OnlyMatching && !itm.MatchesQuery()) {
+			continue
+		}
+		if idx, ok := resultSet[itm.Key]; ok {
+			// duplicate found
+			results[idx].Stats.add(rec, idx, seen)
+			if results[idx].Score < itm.Score {
+				results[idx].Record = rec
+				results[idx].Score = itm.Score
+			}
+			continue
+		}
+		resultSet[itm.Key] = len(results)
+		results = append(results, Result{Record: rec, Score: itm.Score})
+		results[len(results)-1].Stats.add(rec, len(results)-1, seen)
+	}
+	// frequently used commands rank higher
+	for i := range results {
+		results[i].Score += frequencyScore(query.ranking, results[i].Stats.Count)
+	}
+	return sortAndLimit(results, opts.Limit), nil
+}
+
+// SearchRaw searches records without context - only the query and time are used for scoring
+// OnlyMatching option is ignored
+// Returns error when the context gets canceled
+func SearchRaw(ctx context.Context, records []recordint.SearchApp, query Query, opts Options, debug bool) ([]Result, error) {
+	var results []Result
+	resultSet := make(map[string]bool)
+	filter := opts.Filter.withTimeRange(query.timeRange())
+	for i, rec := range records {
+		if i%cancelCheckPeriod == 0 && ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if !filter.Match(rec) {
+			continue
+		}
+		itm, err := NewRawItemFromRecordForQuery(rec, query, debug)
+		if err != nil || resultSet[itm.Key] {
+			continue
+		}
+		resultSet[itm.Key] = true
+		results = append(results, Result{Record: rec, Score: itm.Score})
+	}
+	return sortAndLimit(results, opts.Limit), nil
+}
+
+func sortAndLimit(results []Result, limit int) []Result {
+	sort.SliceStable(results, func(p, q int) bool {
+		return results[p].Score > results[q].Score
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
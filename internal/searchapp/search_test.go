@@ -0,0 +1,107 @@
+package searchapp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/curusarn/resh/internal/cfg"
+	"github.com/curusarn/resh/internal/recordint"
+	"go.uber.org/zap"
+)
+
+func TestSearchMergesDuplicates(t *testing.T) {
+	sugar := zap.NewNop().Sugar()
+	query := NewQueryFromString(sugar, "git", QueryContext{Host: "laptop", Pwd: "/home/user"}, cfg.DefaultRanking, false, false)
+	records := []recordint.SearchApp{
+		{CmdLine: "git status", RecordID: "a", Host: "laptop", Pwd: "/tmp", Time: 1},
+		{CmdLine: "git status", RecordID: "b", Host: "laptop", Pwd: "/home/user", Time: 2},
+		{CmdLine: "git log", RecordID: "c", Host: "laptop", Pwd: "/tmp", Time: 3},
+		{CmdLine: "ls", RecordID: "d", Host: "laptop", Pwd: "/tmp", Time: 4},
+	}
+	results, err := Search(context.Background(), records, query, Options{OnlyMatching: true}, false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(results))
+	}
+	if results[0].Record.RecordID != "b" {
+		t.Fatalf("Expected best scoring duplicate first, got record %s", results[0].Record.RecordID)
+	}
+	expected := Stats{Count: 2, FirstSeen: 1, LastSeen: 2, DirCount: 2, DeviceCount: 1}
+	if results[0].Stats != expected {
+		t.Fatalf("Unexpected stats: %+v", results[0].Stats)
+	}
+
+	results, err = Search(context.Background(), records, query, Options{Limit: 1}, false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Expected 1 result with limit, got %d", len(results))
+	}
+}
+
+func TestSearchCanceled(t *testing.T) {
+	sugar := zap.NewNop().Sugar()
+	query := NewQueryFromString(sugar, "git", QueryContext{Host: "laptop", Pwd: "/home/user"}, cfg.DefaultRanking, false, false)
+	records := []recordint.SearchApp{{CmdLine: "git status", Host: "laptop"}}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := Search(ctx, records, query, Options{}, false)
+	if err == nil {
+		t.Fatal("Expected error for canceled search")
+	}
+}
+
+func TestSearchTimeRange(t *testing.T) {
+	sugar := zap.NewNop().Sugar()
+	day := func(d int) float64 {
+		return float64(time.Date(2024, 5, d, 12, 0, 0, 0, time.Local).Unix())
+	}
+	records := []recordint.SearchApp{
+		{CmdLine: "git status", RecordID: "a", Time: day(1)},
+		{CmdLine: "git log", RecordID: "b", Time: day(10)},
+		{CmdLine: "git push", RecordID: "c", Time: day(20)},
+		recordint.NewSearchAppFromCmdLine("git pull"),
+	}
+	opts := Options{Filter: Filter{Since: time.Date(2024, 5, 5, 0, 0, 0, 0, time.Local)}}
+	data := map[string][]string{
+		"git":                                    {"b", "c"},
+		"git before:2024-05-15":                  {"b"},
+		"git since:2024-05-15":                   {"c"},
+		"git since:2024-04-01 before:2024-05-15": {"b"},
+	}
+	for input, expected := range data {
+		query := NewQueryFromString(sugar, input, QueryContext{}, cfg.DefaultRanking, false, false)
+		results, err := Search(context.Background(), records, query, opts, false)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var ids []string
+		for _, res := range results {
+			ids = append(ids, res.Record.RecordID)
+		}
+		if len(ids) != len(expected) {
+			t.Fatalf("Unexpected results for '%s': %v", input, ids)
+		}
+		for _, id := range expected {
+			found := false
+			for _, resID := range ids {
+				found = found || resID == id
+			}
+			if !found {
+				t.Fatalf("Expected record %s in results for '%s', got %v", id, input, ids)
+			}
+		}
+	}
+
+	results, err := SearchRaw(context.Background(), records, NewRawQueryFromString("git", false, false), opts, false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Expected raw search to respect the time filter, got %d results", len(results))
+	}
+}
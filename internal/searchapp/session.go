@@ -0,0 +1,25 @@
+package searchapp
+
+import (
+	"strings"
+
+	"github.com/curusarn/resh/internal/recordint"
+)
+
+// DrawSessionLine renders record in session replay - marked records are part of the selected range
+func DrawSessionLine(rec recordint.SearchApp, marked bool, width int) string {
+	const timeFormat = "2006-01-02 15:04:05"
+	pwd := rec.Pwd
+	if rec.Home != "" {
+		pwd = strings.Replace(pwd, rec.Home, "~", 1)
+	}
+	prefix := "  "
+	if marked {
+		prefix = "> "
+	}
+	line := rightCutString(prefix+unixToTime(rec.Time).Format(timeFormat)+"  "+pwd+"  "+replaceNewLines(trimCmdLine(rec.CmdLine)), width)
+	if marked {
+		return highlightMatch(line)
+	}
+	return line
+}
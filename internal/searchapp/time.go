@@ -1,10 +1,71 @@
 package searchapp
 
 import (
+	"fmt"
 	"strconv"
 	"time"
 )
 
+var timeLayouts = []string{
+	"2006-01-02",
+	"2006-01-02 15:04",
+	"2006-01-02 15:04:05",
+	time.RFC3339,
+}
+
+var relativeTimeUnits = map[byte]time.Duration{
+	's': time.Second,
+	'm': time.Minute,
+	'h': time.Hour,
+	'd': 24 * time.Hour,
+	'w': 7 * 24 * time.Hour,
+	'y': 365 * 24 * time.Hour,
+}
+
+// ParseTime parses absolute time (e.g. "2024-01-31", "2024-01-31 14:00", RFC3339)
+// or relative time (e.g. "30m", "12h", "3d", "2w", "1y") which is subtracted from now
+// Absolute times without timezone are in local time
+func ParseTime(str string, now time.Time) (time.Time, error) {
+	if len(str) >= 2 {
+		unit, found := relativeTimeUnits[str[len(str)-1]]
+		count, err := strconv.Atoi(str[:len(str)-1])
+		if found && err == nil && count >= 0 {
+			return now.Add(-time.Duration(count) * unit), nil
+		}
+	}
+	for _, layout := range timeLayouts {
+		tm, err := time.ParseInLocation(layout, str, time.Local)
+		if err == nil {
+			return tm, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized time '%s' - use e.g. '2024-01-31', '2024-01-31 14:00' or '3d'", str)
+}
+
+// Time ranges of the search app time filter
+const (
+	TimeRangeAll   = "all"
+	TimeRangeToday = "today"
+	TimeRangeWeek  = "week"
+	TimeRangeMonth = "month"
+)
+
+// TimeRangeStart returns start of the time range that contains now - zero time for TimeRangeAll and unknown ranges
+// Weeks start on Monday
+func TimeRangeStart(timeRange string, now time.Time) time.Time {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch timeRange {
+	case TimeRangeToday:
+		return today
+	case TimeRangeWeek:
+		daysSinceMonday := (int(today.Weekday()) + 6) % 7
+		return today.AddDate(0, 0, -daysSinceMonday)
+	case TimeRangeMonth:
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	}
+	return time.Time{}
+}
+
 func formatTimeRelativeLongest(tm time.Time) string {
 	tmSince := time.Since(tm)
 	hrs := tmSince.Hours()
@@ -4,6 +4,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/curusarn/resh/internal/bus"
 	"github.com/curusarn/resh/internal/recordint"
 	"github.com/mitchellh/go-ps"
 	"go.uber.org/zap"
@@ -12,17 +13,17 @@ import (
 type sesswatch struct {
 	sugar *zap.SugaredLogger
 
-	sessionsToDrop []chan string
+	sessionsToDrop *bus.Bus[string]
 	sleepSeconds   uint
 
 	watchedSessions map[string]bool
 	mutex           sync.Mutex
 }
 
-// Go runs the session watcher - watches sessions and sends
+// Go runs the session watcher - watches sessions and publishes the ones that ended
 func Go(sugar *zap.SugaredLogger,
-	sessionsToWatch chan recordint.SessionInit, sessionsToWatchRecords chan recordint.Collect,
-	sessionsToDrop []chan string, sleepSeconds uint) {
+	sessionsToWatch <-chan recordint.SessionInit, sessionsToWatchRecords <-chan recordint.Collect,
+	sessionsToDrop *bus.Bus[string], sleepSeconds uint) {
 
 	sw := sesswatch{
 		sugar:           sugar.With("module", "sesswatch"),
@@ -33,7 +34,7 @@ func Go(sugar *zap.SugaredLogger,
 	go sw.waiter(sessionsToWatch, sessionsToWatchRecords)
 }
 
-func (s *sesswatch) waiter(sessionsToWatch chan recordint.SessionInit, sessionsToWatchRecords chan recordint.Collect) {
+func (s *sesswatch) waiter(sessionsToWatch <-chan recordint.SessionInit, sessionsToWatchRecords <-chan recordint.Collect) {
 	for {
 		func() {
 			select {
@@ -85,11 +86,8 @@ func (s *sesswatch) watcher(sugar *zap.SugaredLogger, sessionID string, sessionP
 				defer s.mutex.Unlock()
 				s.watchedSessions[sessionID] = false
 			}()
-			for _, ch := range s.sessionsToDrop {
-				sugar.Debugw("Sending 'drop session' message ...")
-				ch <- sessionID
-				sugar.Debugw("Sending 'drop session' message DONE")
-			}
+			sugar.Debugw("Publishing 'drop session' message")
+			s.sessionsToDrop.Publish(sessionID)
 			break
 		}
 	}
@@ -5,24 +5,27 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"strconv"
+	"time"
 
+	"github.com/curusarn/resh/internal/cfg"
 	"github.com/curusarn/resh/internal/httpclient"
 	"github.com/curusarn/resh/internal/msg"
 )
 
-func get(port int) (*http.Response, error) {
-	url := "http://localhost:" + strconv.Itoa(port) + "/status"
-	client := httpclient.New()
-	resp, err := client.Get(url)
+func get(config cfg.Config) (*http.Response, error) {
+	client, err := httpclient.New(config, 500*time.Millisecond)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Get(httpclient.URL(config, "/status"))
 	if err != nil {
 		return nil, fmt.Errorf("error while GET'ing daemon /status: %w", err)
 	}
 	return resp, nil
 }
 
-func IsDaemonRunning(port int) (bool, error) {
-	resp, err := get(port)
+func IsDaemonRunning(config cfg.Config) (bool, error) {
+	resp, err := get(config)
 	if err != nil {
 		return false, err
 	}
@@ -30,8 +33,8 @@ func IsDaemonRunning(port int) (bool, error) {
 	return true, nil
 }
 
-func GetDaemonStatus(port int) (*msg.StatusResponse, error) {
-	resp, err := get(port)
+func GetDaemonStatus(config cfg.Config) (*msg.StatusResponse, error) {
+	resp, err := get(config)
 	if err != nil {
 		return nil, err
 	}
@@ -1,8 +1,9 @@
 package record
 
 type V1 struct {
-	// future-proofing so that we can add this later without version bump
-	// deleted, favorite
+	// flags set by the user from the search app
+	// deleted records are kept in the history file but never shown in search
+	// favorite records are ranked higher in search
 	Deleted  bool `json:"deleted,omitempty"`
 	Favorite bool `json:"favorite,omitempty"`
 
@@ -28,9 +29,7 @@ type V1 struct {
 	// git info
 	// origin is the most important
 	GitOriginRemote string `json:"gitOriginRemote"`
-	// TODO: add GitBranch (v2 ?)
-	//       maybe branch could be useful - e.g. in monorepo ??
-	// GitBranch string `json:"gitBranch"`
+	// git branch is part of V2
 
 	// what is this for ??
 	// session watching needs this
@@ -0,0 +1,56 @@
+package record
+
+// V2 adds git branch, commit, repository root and worktree state to V1
+type V2 struct {
+	// flags set by the user from the search app
+	// deleted records are kept in the history file but never shown in search
+	// favorite records are ranked higher in search
+	Deleted  bool `json:"deleted,omitempty"`
+	Favorite bool `json:"favorite,omitempty"`
+
+	// cmdline, exitcode
+	CmdLine  string `json:"cmdLine"`
+	ExitCode int    `json:"exitCode"`
+
+	DeviceID  string `json:"deviceID"`
+	SessionID string `json:"sessionID"`
+	RecordID  string `json:"recordID"`
+
+	// paths
+	Home    string `json:"home"`
+	Pwd     string `json:"pwd"`
+	RealPwd string `json:"realPwd"`
+
+	// Device is set during installation/setup
+	// It is stored in RESH configuration
+	Device string `json:"device"`
+
+	// git info
+	// origin is the most important
+	GitOriginRemote string `json:"gitOriginRemote"`
+	// branch is useful in monorepos where all commands share the same remote
+	// empty when HEAD is detached
+	GitBranch string `json:"gitBranch,omitempty"`
+	// HEAD commit
+	GitCommit string `json:"gitCommit,omitempty"`
+	// repository root - git rev-parse --show-toplevel
+	GitToplevel string `json:"gitToplevel,omitempty"`
+	// worktree had uncommitted changes to tracked files
+	GitDirty bool `json:"gitDirty,omitempty"`
+
+	// environment variables from the CaptureEnv config option (e.g. AWS_PROFILE, VIRTUAL_ENV)
+	// unset and empty variables are left out
+	Env map[string]string `json:"env,omitempty"`
+
+	// time (before), duration of command
+	// see V1 for why these are strings
+	Time     string `json:"time"`
+	Duration string `json:"duration"`
+
+	// records come in two parts (collect and postcollect)
+	PartOne        bool `json:"partOne,omitempty"` // false => part two
+	PartsNotMerged bool `json:"partsNotMerged,omitempty"`
+
+	// special flag -> not an actual record but an session end
+	SessionExit bool `json:"sessionExit,omitempty"`
+}
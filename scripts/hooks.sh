@@ -116,6 +116,7 @@ __resh_widget_control_R() {
 
     local status_code
     local git_remote; git_remote="$(git remote get-url origin 2>/dev/null)"
+    local git_branch; git_branch="$(git rev-parse --abbrev-ref HEAD 2>/dev/null)"
     if [ "$(resh-cli -version)" != "$__RESH_VERSION" ] && [ -z "${__RESH_NO_RELOAD-}" ]; then
         source ~/.resh/shellrc
         # Show reload message from the updated shell files
@@ -126,6 +127,7 @@ __resh_widget_control_R() {
     fi
     BUFFER=$(resh-cli -requireVersion "$__RESH_VERSION" \
         --git-remote "$git_remote" \
+        --git-branch "$git_branch" \
         --pwd "$PWD" \
         --query "$BUFFER" \
         --session-id "$__RESH_SESSION_ID" \
@@ -167,8 +169,10 @@ resh() {
     fi
     local buffer
     local git_remote; git_remote="$(git remote get-url origin 2>/dev/null)"
+    local git_branch; git_branch="$(git rev-parse --abbrev-ref HEAD 2>/dev/null)"
     buffer=$(resh-cli -requireVersion "$__RESH_VERSION" \
         --git-remote "$git_remote" \
+        --git-branch "$git_branch" \
         --pwd "$PWD" \
         --session-id "$__RESH_SESSION_ID" \
         "$@"
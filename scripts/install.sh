@@ -19,9 +19,9 @@ trap cleanup EXIT INT TERM
 # /usr/bin/zsh -> zsh
 login_shell=$(echo "$SHELL" | rev | cut -d'/' -f1 | rev)
 
-if [ "$login_shell" != bash ] && [ "$login_shell" != zsh ]; then
+if [ "$login_shell" != bash ] && [ "$login_shell" != zsh ] && [ "$login_shell" != fish ]; then
     echo "* UNSUPPORTED login shell: $login_shell"
-    echo " -> RESH supports zsh and bash"
+    echo " -> RESH supports zsh, bash and fish"
     echo
     if [ -z "${RESH_INSTALL_IGNORE_LOGIN_SHELL-}" ]; then
         echo 'EXITING!'
@@ -65,9 +65,24 @@ else
     fi
 fi
 
-if [ "$bash_ok" != 1 ] && [ "$zsh_ok" != 1 ]; then
+# fish is optional - don't report it when it's not installed
+fish_ok=0
+if fish --version >/dev/null 2>&1; then
+    fish_version=$(fish -c 'echo $version')
+    fish_version_major=$(echo "$fish_version" | cut -d'.' -f1)
+    fish_version_minor=$(echo "$fish_version" | cut -d'.' -f2)
+    if [ "$fish_version_major" -lt 3 ] || { [ "$fish_version_major" -eq 3 ] && [ "$fish_version_minor" -lt 1 ]; }; then
+        echo "* UNSUPPORTED fish version: $fish_version"
+        echo " -> Update to fish 3.1+ if you want to use RESH in fish"
+        echo
+    else
+        fish_ok=1
+    fi
+fi
+
+if [ "$bash_ok" != 1 ] && [ "$zsh_ok" != 1 ] && [ "$fish_ok" != 1 ]; then
     echo "* You have no shell that is supported by RESH!"
-    echo " -> Please install/update zsh or bash and run this installation again"
+    echo " -> Please install/update zsh, bash or fish and run this installation again"
     echo
     if [ -z "${RESH_INSTALL_IGNORE_NO_SHELL-}" ]; then
         echo 'EXITING!'
@@ -122,6 +137,7 @@ cp -f submodules/bash-preexec/bash-preexec.sh ~/.bash-preexec.sh
 cp -f submodules/bash-zsh-compat-widgets/bindfunc.sh ~/.resh/bindfunc.sh
 
 cp -f scripts/shellrc.sh ~/.resh/shellrc
+cp -f scripts/shellrc.fish ~/.resh/shellrc.fish
 cp -f scripts/resh-daemon-start.sh ~/.resh/bin/resh-daemon-start
 cp -f scripts/resh-daemon-stop.sh ~/.resh/bin/resh-daemon-stop
 cp -f scripts/resh-daemon-restart.sh ~/.resh/bin/resh-daemon-restart
@@ -158,6 +174,13 @@ if [ "$zsh_ok" = 1 ]; then
     fi
 fi
 
+# Only add shell directives into fish if it passed version checks
+if [ "$fish_ok" = 1 ]; then
+    # fish sources all files in conf.d
+    mkdir -p ~/.config/fish/conf.d
+    echo 'test -f ~/.resh/shellrc.fish; and source ~/.resh/shellrc.fish # this file was added by RESH' > ~/.config/fish/conf.d/resh.fish
+fi
+
 ~/.resh/bin/resh-daemon-start
 
 # bright green
@@ -179,7 +202,7 @@ printf '
     Relevant results are displayed first based on current directory, git repo, and exit status.
 
     RESH will locally record and save shell history with context (directory, time, exit status, ...)
-    Start using RESH right away because bash and zsh history are also searched.
+    Start using RESH right away because bash, zsh and fish history are also searched.
 
     Update RESH by running: reshctl update
     Thank you for using RESH!
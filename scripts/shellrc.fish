@@ -0,0 +1,175 @@
+# RESH integration for fish
+# Installed as ~/.resh/shellrc.fish and sourced from ~/.config/fish/conf.d/resh.fish
+# Keep in sync with hooks.sh and shellrc.sh - see backwards compatibility notes in hooks.sh
+
+status is-interactive; or return
+
+contains ~/.resh/bin $PATH; or set -gx PATH $PATH ~/.resh/bin
+
+set -g __RESH_SHELL fish
+
+set -gx __RESH_VERSION (resh-collect -version)
+
+function __resh_reload_msg
+    printf '\n'
+    printf '┌──────────────────────────────────────────────────────────────┐\n'
+    printf '│ New version of RESH shell files was loaded in this terminal. │\n'
+    printf '│ This is an informative message - no action is necessary.     │\n'
+    printf '│ Please restart this terminal if you encounter any issues.    │\n'
+    printf '└──────────────────────────────────────────────────────────────┘\n'
+    printf '\n'
+end
+
+# Reload shell files when RESH was updated in the background
+# Returns 1 if the shell files were reloaded
+# __RESH_NO_RELOAD prevents recursive reloads
+function __resh_reload_if_needed --argument-names binary
+    set -l binary_version ($binary -version)
+    if test "$binary_version" != "$__RESH_VERSION"; and not set -q __RESH_NO_RELOAD
+        source ~/.resh/shellrc.fish
+        # Show reload message from the updated shell files
+        __resh_reload_msg
+        return 1
+    end
+    return 0
+end
+
+# (pre)collect
+function __resh_preexec --on-event fish_preexec --argument-names cmdline
+    if not __resh_reload_if_needed resh-collect
+        # Rerun self but prevent another reload. Extra protection against infinite recursion.
+        set -g __RESH_NO_RELOAD 1
+        __resh_preexec $cmdline
+        set -e __RESH_NO_RELOAD
+        return
+    end
+    set -g __RESH_COLLECT 1
+    set -g __RESH_RECORD_ID (resh-generate-uuid)
+    set -l git_remote (git remote get-url origin 2>/dev/null)
+    set -g __RESH_RT_BEFORE (resh-get-epochtime)
+    resh-collect -requireVersion "$__RESH_VERSION" \
+        --git-remote "$git_remote" \
+        --home "$HOME" \
+        --pwd "$PWD" \
+        --record-id "$__RESH_RECORD_ID" \
+        --session-id "$__RESH_SESSION_ID" \
+        --session-pid "$fish_pid" \
+        --shell "$__RESH_SHELL" \
+        --shlvl "$SHLVL" \
+        --time "$__RESH_RT_BEFORE" \
+        --cmd-line "$cmdline"
+end
+
+# postcollect
+function __resh_postexec --on-event fish_postexec
+    # Get status first before it gets overriden by another command.
+    set -l exit_code $status
+    # Don't do anything if __resh_preexec was not called.
+    set -q __RESH_COLLECT; or return
+    if not __resh_reload_if_needed resh-postcollect
+        # Skip recording part2 for this command - see __resh_precmd in hooks.sh
+        return
+    end
+    set -e __RESH_COLLECT
+
+    set -l rt_after (resh-get-epochtime)
+    resh-postcollect -requireVersion "$__RESH_VERSION" \
+        --exit-code "$exit_code" \
+        --record-id "$__RESH_RECORD_ID" \
+        --session-id "$__RESH_SESSION_ID" \
+        --shlvl "$SHLVL" \
+        --time-after "$rt_after" \
+        --time-before "$__RESH_RT_BEFORE"
+end
+
+function __resh_session_init
+    resh-session-init -requireVersion "$__RESH_VERSION" \
+        --session-id "$__RESH_SESSION_ID" \
+        --session-pid "$fish_pid"
+end
+
+function __resh_widget_control_R
+    if not __resh_reload_if_needed resh-cli
+        set -g __RESH_NO_RELOAD 1
+        __resh_widget_control_R
+        set -e __RESH_NO_RELOAD
+        return
+    end
+    set -l git_remote (git remote get-url origin 2>/dev/null)
+    set -l git_branch (git rev-parse --abbrev-ref HEAD 2>/dev/null)
+    set -l query (commandline | string collect)
+    # output is split into lines by fish - join them back
+    set -l buffer (resh-cli -requireVersion "$__RESH_VERSION" \
+        --git-remote "$git_remote" \
+        --git-branch "$git_branch" \
+        --pwd "$PWD" \
+        --query "$query" \
+        --session-id "$__RESH_SESSION_ID")
+    set -l status_code $status
+    set buffer (string join \n -- $buffer | string collect)
+    switch $status_code
+        case 111
+            # execute
+            commandline --replace -- "$buffer"
+            commandline --function execute
+        case 0
+            # paste
+            commandline --replace -- "$buffer"
+        case 130
+            # aborted
+        case '*'
+            echo "RESH SEARCH APP failed"
+            printf "%s" "$buffer" >&2
+    end
+    commandline --function repaint
+end
+
+# Wrapper for resh-cli for calling resh directly
+function resh
+    if not __resh_reload_if_needed resh-cli
+        set -g __RESH_NO_RELOAD 1
+        resh $argv
+        set -e __RESH_NO_RELOAD
+        return
+    end
+    set -l git_remote (git remote get-url origin 2>/dev/null)
+    set -l git_branch (git rev-parse --abbrev-ref HEAD 2>/dev/null)
+    set -l buffer (resh-cli -requireVersion "$__RESH_VERSION" \
+        --git-remote "$git_remote" \
+        --git-branch "$git_branch" \
+        --pwd "$PWD" \
+        --session-id "$__RESH_SESSION_ID" \
+        $argv)
+    set -l status_code $status
+    set buffer (string join \n -- $buffer | string collect)
+    switch $status_code
+        case 111
+            # execute
+            echo "$buffer"
+            eval "$buffer"
+        case 0
+            # paste
+            echo "$buffer"
+        case 130
+        case '*'
+            printf "%s" "$buffer" >&2
+    end
+end
+
+function __resh_bind_control_R
+    bind \cr __resh_widget_control_R
+    bind -M insert \cr __resh_widget_control_R 2>/dev/null
+end
+
+resh-daemon-start -q
+
+set -l bind_control_R (resh-config --key BindControlR)
+test "$bind_control_R" = true; and __resh_bind_control_R
+
+# block for anything we only want to do once per session
+# NOTE: nested shells are still the same session
+if not set -q __RESH_SESSION_ID
+    set -gx __RESH_SESSION_ID (resh-generate-uuid)
+
+    __resh_session_init
+end
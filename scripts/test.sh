@@ -11,6 +11,11 @@ for f in scripts/{shellrc,hooks}.sh; do
     ! zsh -n "$f" && echo "Zsh syntax check failed!" && exit 1
 done
 
+if command -v fish >/dev/null; then
+    echo "Checking fish syntax of scripts/shellrc.fish ..."
+    ! fish -n scripts/shellrc.fish && echo "Fish syntax check failed!" && exit 1
+fi
+
 if [ "$1" = "--all" ]; then
 	for sh in bash zsh; do
 	    echo "Running functions in scripts/shellrc.sh using $sh ..."